@@ -3,6 +3,7 @@ module github.com/lucasefe/seedup
 go 1.24
 
 require (
+	github.com/klauspost/compress v1.18.0
 	github.com/lib/pq v1.10.9
 	github.com/lucasefe/dbml v0.0.0-20260115143727-50bcf33e6f2d
 	github.com/pressly/goose/v3 v3.26.0
@@ -5,10 +5,12 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/lucasefe/seedup/pkg/db"
+	"github.com/lucasefe/seedup/pkg/reset"
 )
 
 var adminURL string
@@ -26,6 +28,7 @@ func newDBCmd() *cobra.Command {
 	cmd.AddCommand(newDBDropCmd())
 	cmd.AddCommand(newDBCreateCmd())
 	cmd.AddCommand(newDBSetupCmd())
+	cmd.AddCommand(newDBResetCmd())
 
 	return cmd
 }
@@ -158,6 +161,89 @@ This is a destructive operation that will drop and recreate the databases.`,
 	return cmd
 }
 
+func newDBResetCmd() *cobra.Command {
+	var (
+		force        bool
+		noTest       bool
+		seedName     string
+		snapshot     bool
+		fromSnapshot bool
+		restart      bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "reset",
+		Short: "Rebuild the database: setup, seed, migrate",
+		Long: `Rebuild the database in one go. This runs, in order:
+
+  1. db setup               (drop and recreate the database, user and permissions)
+  2. seed apply <seed>      (initial migration + seed data)
+  3. migrate up             (remaining migrations)
+  4. snapshot               (with --snapshot)
+
+Each step prints its duration. If a step fails, running reset again resumes
+at the failed step; use --restart to start over.
+
+With --snapshot, the rebuilt database is copied to <db>_snapshot.
+--from-snapshot restores the database from that copy instead of running the
+pipeline, which is much faster; without a snapshot it runs the full pipeline.
+
+This is a destructive operation that will drop and recreate the database.
+
+Example:
+  seedup db reset --seed dev
+  seedup db reset --seed dev --snapshot
+  seedup db reset --seed dev --from-snapshot --snapshot`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dbURL := getDatabaseURL()
+			if dbURL == "" {
+				return fmt.Errorf("database URL required (use -d flag or DATABASE_URL env)")
+			}
+			if seedName == "" {
+				return fmt.Errorf("--seed is required")
+			}
+
+			cfg, err := db.ParseDatabaseURL(dbURL)
+			if err != nil {
+				return err
+			}
+
+			if !force {
+				if !confirmAction(fmt.Sprintf("This will DROP and rebuild database '%s'. Continue?", cfg.Database)) {
+					fmt.Println("Aborted.")
+					return nil
+				}
+			}
+
+			opts := reset.Options{
+				DatabaseURL:   dbURL,
+				AdminURL:      adminURL,
+				MigrationsDir: getMigrationsDir(),
+				SeedDir:       filepath.Join(getSeedDir(), seedName),
+				SkipTestDB:    noTest,
+				Snapshot:      snapshot,
+				FromSnapshot:  fromSnapshot,
+				Restart:       restart,
+			}
+
+			if err := reset.Run(context.Background(), opts); err != nil {
+				return err
+			}
+
+			fmt.Println("Database reset completed successfully.")
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "Skip confirmation prompt")
+	cmd.Flags().BoolVar(&noTest, "no-test", false, "Skip creating the test database")
+	cmd.Flags().StringVar(&seedName, "seed", "", "Seed set to apply (e.g. dev)")
+	cmd.Flags().BoolVar(&snapshot, "snapshot", false, "Save the rebuilt database as <db>_snapshot")
+	cmd.Flags().BoolVar(&fromSnapshot, "from-snapshot", false, "Restore from <db>_snapshot when it exists instead of rebuilding")
+	cmd.Flags().BoolVar(&restart, "restart", false, "Run every step, ignoring a failed earlier run")
+	return cmd
+}
+
 func confirmAction(prompt string) bool {
 	fmt.Printf("%s [y/N]: ", prompt)
 	reader := bufio.NewReader(os.Stdin)
@@ -2,6 +2,7 @@ package cli
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 
 	"github.com/spf13/cobra"
@@ -10,7 +11,28 @@ import (
 )
 
 func newFlattenCmd() *cobra.Command {
-	return &cobra.Command{
+	var (
+		matviewsNoData bool
+		sequenceValues bool
+		tablespaces    bool
+		dryRun         bool
+		backupDir      string
+		force          bool
+		verify         bool
+		fromMigrations bool
+		until          int64
+		scratchURL     string
+		refTables      string
+		schemaList     string
+		excludeSchemas string
+		tables         string
+		excludeTables  string
+		noFunctions    bool
+		noTriggers     bool
+		noExtensions   bool
+	)
+
+	cmd := &cobra.Command{
 		Use:   "flatten",
 		Short: "Flatten migrations into a single initial migration",
 		Long: `Flatten all applied migrations into a single initial migration.
@@ -19,20 +41,95 @@ This dumps the current schema and replaces all migration files with a single fil
 This is useful for:
 - Reducing the number of migration files in a project
 - Creating a clean starting point for new environments
-- Simplifying migration history`,
+- Simplifying migration history
+
+Use --schemas/--exclude-schemas and --tables/--exclude-tables (glob patterns,
+bare or schema-qualified) to leave out objects owned by other teams in a
+shared database.
+
+Flatten refuses to run if the migrations directory has uncommitted changes,
+since the files it replaces could not be recovered. Use --dry-run to preview
+the result, --backup-dir to keep the replaced files, or --force to skip the
+check.
+
+Use --verify to apply the new initial migration to a scratch database
+(--scratch-url, dropped and recreated) and compare its schema with the source
+before any file is replaced.
+
+Use --from-migrations to build the scratch database by running every
+migration in the directory, and flatten from it instead of -d. The schema
+then comes from the repository's history rather than a live database.
+
+Use --until <version> to squash only the migrations up to that version. The
+schema as of that version is built in the scratch database, and later
+migration files are kept.
+
+Use --reference-tables to embed the rows of lookup tables (countries, plans,
+feature flags) in the initial migration, so every environment starts with
+them. Rows are read from -d, even with --from-migrations.
+
+Examples:
+  seedup flatten --dry-run
+  seedup flatten --reference-tables public.countries,public.plans
+  seedup flatten --verify --scratch-url postgres://localhost/myapp_flatten_check
+  seedup flatten --from-migrations --scratch-url postgres://localhost/myapp_flatten
+  seedup flatten --until 20240601000000 --scratch-url postgres://localhost/myapp_flatten
+  seedup flatten --backup-dir ./migrations-archive
+  seedup flatten --schemas public,billing
+  seedup flatten --exclude-tables 'public.tmp_*' --no-triggers`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			dbURL := getDatabaseURL()
-			if dbURL == "" {
-				return fmt.Errorf("database URL required (use -d flag or DATABASE_URL env)")
+			if (verify || fromMigrations || until > 0) && scratchURL == "" {
+				return fmt.Errorf("--verify, --from-migrations and --until require --scratch-url")
 			}
+			if until > 0 && refTables != "" {
+				return fmt.Errorf("--reference-tables can't be combined with --until")
+			}
+			if err := migrate.CheckScratchURL(getDatabaseURL(), scratchURL); err != nil {
+				return err
+			}
+
+			// The source database is not used when flattening from migrations,
+			// unless reference table rows are read from it
+			var db *sql.DB
+			if (!fromMigrations && until == 0) || refTables != "" {
+				dbURL := getDatabaseURL()
+				if dbURL == "" {
+					return fmt.Errorf("database URL required (use -d flag or DATABASE_URL env)")
+				}
 
-			db, err := pgconn.Open(dbURL)
-			if err != nil {
-				return fmt.Errorf("opening database: %w", err)
+				var err error
+				db, err = pgconn.Open(dbURL)
+				if err != nil {
+					return fmt.Errorf("opening database: %w", err)
+				}
+				defer db.Close()
 			}
-			defer db.Close()
 
-			f := migrate.NewFlattener(db)
+			f := migrate.NewFlattener(db,
+				migrate.WithDryRun(dryRun),
+				migrate.WithBackupDir(backupDir),
+				migrate.WithForce(force),
+				migrate.WithVerify(verify),
+				migrate.WithFromMigrations(fromMigrations),
+				migrate.WithUntil(until),
+				migrate.WithScratchDB(scratchURL, adminURL),
+				migrate.WithReferenceTables(parseList(refTables)),
+				migrate.WithDumpOptions(pgconn.DumpOptions{
+					Schemas:                 parseList(schemaList),
+					ExcludeSchemas:          parseList(excludeSchemas),
+					IncludeTables:           parseList(tables),
+					ExcludeTables:           parseList(excludeTables),
+					NoFunctions:             noFunctions,
+					NoTriggers:              noTriggers,
+					NoExtensions:            noExtensions,
+					MaterializedViewsNoData: matviewsNoData,
+					SequenceValues:          sequenceValues,
+					Tablespaces:             tablespaces,
+				}))
+
+			if dryRun {
+				return f.Flatten(context.Background(), getMigrationsDir())
+			}
 
 			fmt.Println("Flattening migrations...")
 			if err := f.Flatten(context.Background(), getMigrationsDir()); err != nil {
@@ -43,4 +140,30 @@ This is useful for:
 			return nil
 		},
 	}
+
+	cmd.Flags().StringVar(&schemaList, "schemas", "", "Comma-separated schemas to include (default: all non-system schemas)")
+	cmd.Flags().StringVar(&excludeSchemas, "exclude-schemas", "", "Comma-separated schemas to exclude")
+	cmd.Flags().StringVar(&tables, "tables", "", "Comma-separated table glob patterns to include (e.g. 'public.*,billing.invoices')")
+	cmd.Flags().StringVar(&excludeTables, "exclude-tables", "", "Comma-separated table glob patterns to exclude")
+	cmd.Flags().BoolVar(&noFunctions, "no-functions", false, "Skip functions, procedures, operators, aggregates and casts")
+	cmd.Flags().BoolVar(&noTriggers, "no-triggers", false, "Skip triggers and event triggers")
+	cmd.Flags().BoolVar(&noExtensions, "no-extensions", false, "Skip extensions")
+	cmd.Flags().BoolVar(&matviewsNoData, "matviews-no-data", false,
+		"Create materialized views WITH NO DATA instead of populating them")
+	cmd.Flags().BoolVar(&sequenceValues, "sequence-values", false,
+		"Carry over current sequence values (setval) into the initial migration")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the files to remove and the new initial migration without changing anything")
+	cmd.Flags().StringVar(&backupDir, "backup-dir", "", "Move replaced migration files into this directory instead of deleting them")
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "Flatten even if the migrations directory has uncommitted changes")
+	cmd.Flags().BoolVar(&verify, "verify", false, "Check the new initial migration reproduces the schema before replacing files")
+	cmd.Flags().BoolVar(&fromMigrations, "from-migrations", false, "Flatten from a scratch database built by running all migrations, instead of -d")
+	cmd.Flags().Int64Var(&until, "until", 0, "Only squash migrations up to and including this version, keeping later files")
+	cmd.Flags().StringVar(&scratchURL, "scratch-url", "", "Throwaway database used by --verify, --from-migrations and --until (dropped and recreated)")
+	cmd.Flags().StringVar(&adminURL, "admin-url", "", "Admin database URL used to create and drop the scratch database")
+	cmd.Flags().StringVar(&refTables, "reference-tables", "",
+		"Comma-separated tables whose rows are embedded in the initial migration as INSERTs")
+	cmd.Flags().BoolVar(&tablespaces, "tablespaces", false,
+		"Keep the TABLESPACE of tables stored outside the default tablespace")
+
+	return cmd
 }
@@ -7,6 +7,7 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/lucasefe/seedup/pkg/migrate"
+	"github.com/lucasefe/seedup/pkg/pgconn"
 )
 
 func newMigrateCmd() *cobra.Command {
@@ -21,6 +22,7 @@ func newMigrateCmd() *cobra.Command {
 	cmd.AddCommand(newMigrateDownCmd())
 	cmd.AddCommand(newMigrateStatusCmd())
 	cmd.AddCommand(newMigrateCreateCmd())
+	cmd.AddCommand(newMigrateRebaselineCmd())
 
 	return cmd
 }
@@ -107,3 +109,64 @@ func newMigrateCreateCmd() *cobra.Command {
 		},
 	}
 }
+
+func newMigrateRebaselineCmd() *cobra.Command {
+	var (
+		dryRun         bool
+		scratchURL     string
+		schemaList     string
+		excludeSchemas string
+		tables         string
+		excludeTables  string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "rebaseline",
+		Short: "Rewrite goose_db_version to match a flattened migrations directory",
+		Long: `Rewrite goose_db_version in an existing database to match a flattened
+migrations directory, so the environment can adopt the flatten.
+
+The schema is verified first: the migrations are run in a scratch database
+(--scratch-url, dropped and recreated) up to the newest version applied in the
+target, and the two schemas are compared. Only if they match is the version
+history replaced, in a single transaction.
+
+Pass the same filtering flags used for flatten, so objects it left out are
+not reported as differences.
+
+Example:
+  seedup migrate rebaseline -d "$DATABASE_URL" --scratch-url postgres://localhost/myapp_rebaseline`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dbURL := getDatabaseURL()
+			if dbURL == "" {
+				return fmt.Errorf("database URL required (use -d flag or DATABASE_URL env)")
+			}
+			if scratchURL == "" {
+				return fmt.Errorf("--scratch-url is required")
+			}
+
+			m := migrate.New(migrate.WithVerbose(verbose))
+			return m.Rebaseline(context.Background(), dbURL, getMigrationsDir(), migrate.RebaselineOptions{
+				ScratchURL: scratchURL,
+				AdminURL:   adminURL,
+				DryRun:     dryRun,
+				DumpOptions: pgconn.DumpOptions{
+					Schemas:        parseList(schemaList),
+					ExcludeSchemas: parseList(excludeSchemas),
+					IncludeTables:  parseList(tables),
+					ExcludeTables:  parseList(excludeTables),
+				},
+			})
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Verify the schema and print the new version history without changing it")
+	cmd.Flags().StringVar(&scratchURL, "scratch-url", "", "Throwaway database used to rebuild the schema (dropped and recreated)")
+	cmd.Flags().StringVar(&adminURL, "admin-url", "", "Admin database URL used to create and drop the scratch database")
+	cmd.Flags().StringVar(&schemaList, "schemas", "", "Comma-separated schemas to compare (default: all non-system schemas)")
+	cmd.Flags().StringVar(&excludeSchemas, "exclude-schemas", "", "Comma-separated schemas to leave out of the comparison")
+	cmd.Flags().StringVar(&tables, "tables", "", "Comma-separated table glob patterns to compare")
+	cmd.Flags().StringVar(&excludeTables, "exclude-tables", "", "Comma-separated table glob patterns to leave out of the comparison")
+
+	return cmd
+}
@@ -2,6 +2,7 @@ package cli
 
 import (
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
@@ -72,3 +73,16 @@ func getSeedDir() string {
 	}
 	return "./seed"
 }
+
+// parseList splits a comma-separated flag value, trimming whitespace and
+// dropping empty entries
+func parseList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -155,11 +155,13 @@ func (m *Manager) SetupPermissions(ctx context.Context, dbURL, adminURL string)
 	}
 	defer targetDB.Close()
 
-	// Grant session_replication_role permission (needed for seed loading to disable triggers)
+	// Grant session_replication_role permission (lets seed loading disable triggers).
+	// GRANT SET ON PARAMETER needs PostgreSQL 15+ and is refused on managed
+	// servers without superuser; seed apply falls back to another strategy then.
 	grantReplicaQuery := fmt.Sprintf("GRANT SET ON PARAMETER session_replication_role TO %s",
 		quoteIdent(cfg.User))
 	if _, err := targetDB.ExecContext(ctx, grantReplicaQuery); err != nil {
-		return fmt.Errorf("granting session_replication_role permission: %w", err)
+		fmt.Printf("Warning: could not grant session_replication_role to '%s' (%v); seed apply will use another trigger strategy\n", cfg.User, err)
 	}
 
 	// Create read-only user and grant permissions
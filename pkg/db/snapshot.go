@@ -0,0 +1,128 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lucasefe/seedup/pkg/pgconn"
+)
+
+// SnapshotName returns the name of the snapshot database of dbName
+func SnapshotName(dbName string) string {
+	return dbName + "_snapshot"
+}
+
+// Snapshot copies the database into its snapshot database
+// (SnapshotName), replacing an existing snapshot. The copy is made with
+// CREATE DATABASE ... TEMPLATE, so other sessions connected to the
+// database are terminated first.
+func (m *Manager) Snapshot(ctx context.Context, dbURL, adminURL string) error {
+	cfg, err := ParseDatabaseURL(dbURL)
+	if err != nil {
+		return err
+	}
+
+	db, err := openAdmin(cfg, adminURL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	snapshot := SnapshotName(cfg.Database)
+	if _, err := db.ExecContext(ctx, fmt.Sprintf("DROP DATABASE IF EXISTS %s WITH (FORCE)", quoteIdent(snapshot))); err != nil {
+		return fmt.Errorf("dropping old snapshot: %w", err)
+	}
+	if err := copyDatabase(ctx, db, cfg.Database, snapshot, cfg.User); err != nil {
+		return fmt.Errorf("creating snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// Restore replaces the database with a copy of its snapshot database. The
+// copy is made under a temporary name first, so the database is only dropped
+// once the copy succeeded.
+func (m *Manager) Restore(ctx context.Context, dbURL, adminURL string) error {
+	cfg, err := ParseDatabaseURL(dbURL)
+	if err != nil {
+		return err
+	}
+
+	db, err := openAdmin(cfg, adminURL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	snapshot := SnapshotName(cfg.Database)
+	restoring := cfg.Database + "_restoring"
+	dropRestoring := fmt.Sprintf("DROP DATABASE IF EXISTS %s WITH (FORCE)", quoteIdent(restoring))
+	if _, err := db.ExecContext(ctx, dropRestoring); err != nil {
+		return fmt.Errorf("dropping leftover %s: %w", restoring, err)
+	}
+	if err := copyDatabase(ctx, db, snapshot, restoring, cfg.User); err != nil {
+		db.ExecContext(ctx, dropRestoring)
+		return fmt.Errorf("restoring snapshot: %w", err)
+	}
+
+	if _, err := db.ExecContext(ctx, fmt.Sprintf("DROP DATABASE IF EXISTS %s WITH (FORCE)", quoteIdent(cfg.Database))); err != nil {
+		db.ExecContext(ctx, dropRestoring)
+		return fmt.Errorf("dropping database: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, fmt.Sprintf("ALTER DATABASE %s RENAME TO %s", quoteIdent(restoring), quoteIdent(cfg.Database))); err != nil {
+		return fmt.Errorf("renaming %s to %s: %w", restoring, cfg.Database, err)
+	}
+
+	return nil
+}
+
+// SnapshotExists reports whether the database has a snapshot database.
+func (m *Manager) SnapshotExists(ctx context.Context, dbURL, adminURL string) (bool, error) {
+	cfg, err := ParseDatabaseURL(dbURL)
+	if err != nil {
+		return false, err
+	}
+
+	db, err := openAdmin(cfg, adminURL)
+	if err != nil {
+		return false, err
+	}
+	defer db.Close()
+
+	var exists bool
+	query := "SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = $1)"
+	if err := db.QueryRowContext(ctx, query, SnapshotName(cfg.Database)).Scan(&exists); err != nil {
+		return false, fmt.Errorf("checking for snapshot: %w", err)
+	}
+	return exists, nil
+}
+
+// openAdmin connects to the admin database
+func openAdmin(cfg *DBConfig, adminURL string) (*sql.DB, error) {
+	if adminURL == "" {
+		adminURL = cfg.AdminURL()
+	}
+
+	db, err := pgconn.Open(adminURL)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to admin database: %w", err)
+	}
+	return db, nil
+}
+
+// copyDatabase creates target as a copy of source, owned by owner. A
+// template database can't have other sessions, so they are terminated.
+func copyDatabase(ctx context.Context, db *sql.DB, source, target, owner string) error {
+	terminate := "SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE datname = $1 AND pid <> pg_backend_pid()"
+	if _, err := db.ExecContext(ctx, terminate, source); err != nil {
+		return fmt.Errorf("closing connections to %s: %w", source, err)
+	}
+
+	query := fmt.Sprintf("CREATE DATABASE %s TEMPLATE %s OWNER %s",
+		quoteIdent(target), quoteIdent(source), quoteIdent(owner))
+	if _, err := db.ExecContext(ctx, query); err != nil {
+		return err
+	}
+	return nil
+}
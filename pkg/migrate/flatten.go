@@ -5,72 +5,363 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/lucasefe/seedup/pkg/executor"
 	"github.com/lucasefe/seedup/pkg/pgconn"
+	"github.com/pressly/goose/v3"
 )
 
 // Flattener consolidates migrations into a single initial migration
 type Flattener struct {
-	db *sql.DB
+	db              *sql.DB
+	dumpOpts        pgconn.DumpOptions
+	dryRun          bool
+	backupDir       string
+	force           bool
+	verify          bool
+	fromMigrations  bool
+	until           int64
+	scratch         string
+	adminURL        string
+	referenceTables []string
+	exec            executor.Executor
+	stdout          io.Writer
+}
+
+// FlattenOption configures a Flattener
+type FlattenOption func(*Flattener)
+
+// WithDumpOptions sets the options used when dumping the schema.
+// The goose version table is always excluded.
+func WithDumpOptions(opts pgconn.DumpOptions) FlattenOption {
+	return func(f *Flattener) {
+		f.dumpOpts = opts
+	}
+}
+
+// WithDryRun prints the files that would be removed and the new initial
+// migration instead of changing the migrations directory
+func WithDryRun(dryRun bool) FlattenOption {
+	return func(f *Flattener) {
+		f.dryRun = dryRun
+	}
+}
+
+// WithBackupDir moves the replaced migration files into dir instead of
+// deleting them
+func WithBackupDir(dir string) FlattenOption {
+	return func(f *Flattener) {
+		f.backupDir = dir
+	}
+}
+
+// WithForce flattens even if the migrations directory has uncommitted changes
+func WithForce(force bool) FlattenOption {
+	return func(f *Flattener) {
+		f.force = force
+	}
+}
+
+// WithScratchDB sets the throwaway database used for verification. It is
+// dropped and recreated, so it must not hold anything of value. adminURL is
+// used to create and drop it and may be empty to use the server default.
+func WithScratchDB(url, adminURL string) FlattenOption {
+	return func(f *Flattener) {
+		f.scratch = url
+		f.adminURL = adminURL
+	}
+}
+
+// WithVerify applies the new initial migration to the scratch database and
+// compares the resulting schema with the source before any migration file is
+// replaced. Flatten fails and leaves the migrations directory untouched if
+// the schemas differ.
+func WithVerify(verify bool) FlattenOption {
+	return func(f *Flattener) {
+		f.verify = verify
+	}
+}
+
+// WithFromMigrations builds the scratch database by running every migration
+// in the migrations directory, and flattens from it instead of the
+// Flattener's database. The schema then reflects the repository's migration
+// history rather than drift in a live database. When combined with
+// WithVerify, verification uses a second scratch database whose name has a
+// "_verify" suffix.
+func WithFromMigrations(fromMigrations bool) FlattenOption {
+	return func(f *Flattener) {
+		f.fromMigrations = fromMigrations
+	}
+}
+
+// WithUntil flattens only the migrations up to and including version,
+// keeping later migration files as they are. The schema as of that version
+// is dumped from a scratch database built from the migrations, so it implies
+// WithFromMigrations.
+func WithUntil(version int64) FlattenOption {
+	return func(f *Flattener) {
+		f.until = version
+	}
+}
+
+// WithReferenceTables embeds the rows of the given tables in the initial
+// migration as INSERT statements, after the schema. Names may be
+// schema-qualified and default to the public schema. The rows are read from
+// the Flattener's database, even when flattening from migrations, so they
+// can't be combined with WithUntil.
+func WithReferenceTables(tables []string) FlattenOption {
+	return func(f *Flattener) {
+		f.referenceTables = tables
+	}
+}
+
+// WithExecutor sets the executor used to run git
+func WithExecutor(exec executor.Executor) FlattenOption {
+	return func(f *Flattener) {
+		f.exec = exec
+	}
+}
+
+// WithOutput sets the writer for progress and dry-run output
+func WithOutput(w io.Writer) FlattenOption {
+	return func(f *Flattener) {
+		f.stdout = w
+	}
 }
 
 // NewFlattener creates a new Flattener with the given database connection
-func NewFlattener(db *sql.DB) *Flattener {
-	return &Flattener{db: db}
+func NewFlattener(db *sql.DB, opts ...FlattenOption) *Flattener {
+	f := &Flattener{
+		db:     db,
+		exec:   executor.New(),
+		stdout: os.Stdout,
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
 }
 
 // Flatten consolidates all applied migrations into a single initial migration
 // It dumps the current schema and replaces all migration files with a single initial file
 func (f *Flattener) Flatten(ctx context.Context, migrationsDir string) error {
+	// Reference rows are read from the source database at its current
+	// schema, which may have columns the schema at the until version lacks
+	if f.until > 0 && len(f.referenceTables) > 0 {
+		return fmt.Errorf("reference tables can't be combined with --until: their rows are read with the current schema of the source database, not the schema at version %d", f.until)
+	}
+
+	// A dry run changes nothing, so it doesn't need a clean directory
+	if !f.dryRun && !f.force {
+		if err := CheckClean(ctx, f.exec, migrationsDir); err != nil {
+			return err
+		}
+	}
+
+	building := f.fromMigrations || f.until > 0
+	verifyURL := f.scratch
+	if building {
+		if f.scratch == "" {
+			return fmt.Errorf("scratch database URL is required")
+		}
+		var err error
+		if verifyURL, err = scratchURLWithSuffix(f.scratch, "_verify"); err != nil {
+			return err
+		}
+	}
+
+	// Scratch databases are dropped and recreated, so refuse any that is
+	// the source database before touching them
+	if f.db != nil {
+		if building {
+			if err := checkScratchTarget(ctx, f.db, f.scratch); err != nil {
+				return err
+			}
+		}
+		if f.verify {
+			if err := checkScratchTarget(ctx, f.db, verifyURL); err != nil {
+				return err
+			}
+		}
+	}
+
+	source := f.db
+	if building {
+		scratch, err := f.buildFromMigrations(ctx, migrationsDir)
+		if err != nil {
+			return err
+		}
+		defer scratch.Close(ctx)
+		source = scratch.DB
+	}
+
 	// Get all applied migration versions
-	versions, err := f.getAppliedVersions(ctx)
+	versions, err := getAppliedVersions(ctx, source)
 	if err != nil {
 		return fmt.Errorf("getting applied versions: %w", err)
 	}
 
 	if len(versions) == 0 {
-		fmt.Println("No applied migrations found, skipping flatten")
+		fmt.Fprintln(f.stdout, "No applied migrations found, skipping flatten")
 		return nil
 	}
 
 	if len(versions) == 1 {
-		fmt.Println("Only one migration found, nothing to flatten")
+		fmt.Fprintln(f.stdout, "Only one migration found, nothing to flatten")
 		return nil
 	}
 
 	// Get the latest version for the new initial migration
 	latestVersion := versions[len(versions)-1]
 
+	if f.verify {
+		if err := checkScratchTarget(ctx, source, verifyURL); err != nil {
+			return err
+		}
+	}
+
 	// Dump the current schema using our custom schema dumper
-	schema, err := f.dumpSchema(ctx)
+	dump, err := dumpSchemaObjects(ctx, source, f.dumpOpts)
 	if err != nil {
 		return fmt.Errorf("dumping schema: %w", err)
 	}
+	schema := dump.SQL()
 
-	// Delete all existing migration files
+	// Reference data comes from the real database, since a scratch database
+	// built from migrations has no rows
+	var data string
+	if len(f.referenceTables) > 0 {
+		if f.db == nil {
+			return fmt.Errorf("reference tables require a source database to read rows from")
+		}
+		if data, err = dumpReferenceData(ctx, f.db, f.referenceTables); err != nil {
+			return err
+		}
+	}
+
+	// Collect the migration files being replaced
+	var files []string
 	for _, version := range versions {
 		pattern := filepath.Join(migrationsDir, version+"_*.sql")
-		matches, _ := filepath.Glob(pattern)
-		for _, match := range matches {
-			if err := os.Remove(match); err != nil {
-				return fmt.Errorf("removing migration file %s: %w", match, err)
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return fmt.Errorf("listing migration files: %w", err)
+		}
+		files = append(files, matches...)
+	}
+
+	initialPath := filepath.Join(migrationsDir, latestVersion+"_initial.sql")
+	content := renderInitialMigration(schema, data, dump.DropSQL())
+
+	// Verify before touching any file, so a failure leaves the old
+	// migrations in place
+	if f.verify {
+		if err := f.verifyMigration(ctx, verifyURL, filepath.Base(initialPath), content, schema); err != nil {
+			return err
+		}
+	}
+
+	if f.dryRun {
+		fmt.Fprintf(f.stdout, "Would remove %d migration files:\n", len(files))
+		for _, file := range files {
+			fmt.Fprintf(f.stdout, "  %s\n", file)
+		}
+		fmt.Fprintf(f.stdout, "\nWould write %s:\n\n", initialPath)
+		f.stdout.Write(content)
+		return nil
+	}
+
+	// Back up or delete all existing migration files
+	for _, file := range files {
+		if f.backupDir != "" {
+			if err := backupFile(file, f.backupDir); err != nil {
+				return fmt.Errorf("backing up migration file %s: %w", file, err)
 			}
+			continue
 		}
+		if err := os.Remove(file); err != nil {
+			return fmt.Errorf("removing migration file %s: %w", file, err)
+		}
+	}
+	if f.backupDir != "" {
+		fmt.Fprintf(f.stdout, "Moved %d migration files to %s\n", len(files), f.backupDir)
 	}
 
 	// Create the new initial migration
-	initialPath := filepath.Join(migrationsDir, latestVersion+"_initial.sql")
-	if err := f.writeInitialMigration(initialPath, schema); err != nil {
+	if err := os.WriteFile(initialPath, content, 0644); err != nil {
 		return fmt.Errorf("writing initial migration: %w", err)
 	}
 
 	return nil
 }
 
-func (f *Flattener) getAppliedVersions(ctx context.Context) ([]string, error) {
+// CheckClean returns an error if migrationsDir has uncommitted or untracked
+// changes, which flattening would destroy. Directories outside a git work
+// tree are not checked. Flatten runs this itself unless forced; callers can
+// use it to fail early, before expensive work that precedes a flatten.
+func CheckClean(ctx context.Context, exec executor.Executor, migrationsDir string) error {
+	if _, err := exec.RunWithOutput(ctx, "git", "-C", migrationsDir, "rev-parse", "--is-inside-work-tree"); err != nil {
+		return nil
+	}
+
+	output, err := exec.RunWithOutput(ctx, "git", "-C", migrationsDir, "status", "--porcelain", "--", ".")
+	if err != nil {
+		return fmt.Errorf("checking migrations directory status: %w", err)
+	}
+	if status := strings.TrimSpace(output); status != "" {
+		return fmt.Errorf("migrations directory %s has uncommitted changes (commit them or use --force):\n%s",
+			migrationsDir, status)
+	}
+	return nil
+}
+
+// backupFile moves file into dir, creating dir if needed
+func backupFile(file, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	dest := filepath.Join(dir, filepath.Base(file))
+	if err := os.Rename(file, dest); err == nil {
+		return nil
+	}
+
+	// Rename fails across filesystems, so fall back to copy and remove
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(dest, data, 0644); err != nil {
+		return err
+	}
+	return os.Remove(file)
+}
+
+// buildFromMigrations creates the scratch database and runs the migrations
+// in migrationsDir against it, up to the Flattener's until version if set
+func (f *Flattener) buildFromMigrations(ctx context.Context, migrationsDir string) (*scratchDB, error) {
+	fmt.Fprintln(f.stdout, "Building scratch database from migrations...")
+
+	scratch, err := openScratchDB(ctx, f.scratch, f.adminURL)
+	if err != nil {
+		return nil, err
+	}
+
+	until := goose.MaxVersion
+	if f.until > 0 {
+		until = f.until
+	}
+	if err := New(WithStdout(os.Stderr)).UpTo(ctx, scratch.url, migrationsDir, until); err != nil {
+		scratch.Close(ctx)
+		return nil, fmt.Errorf("running migrations on scratch database: %w", err)
+	}
+	return scratch, nil
+}
+
+func getAppliedVersions(ctx context.Context, db *sql.DB) ([]string, error) {
 	// First check if the goose_db_version table exists
 	checkQuery := `SELECT EXISTS (
 		SELECT FROM information_schema.tables
@@ -78,7 +369,7 @@ func (f *Flattener) getAppliedVersions(ctx context.Context) ([]string, error) {
 		AND table_name = 'goose_db_version'
 	)`
 	var exists bool
-	if err := f.db.QueryRowContext(ctx, checkQuery).Scan(&exists); err != nil {
+	if err := db.QueryRowContext(ctx, checkQuery).Scan(&exists); err != nil {
 		return nil, err
 	}
 	if !exists {
@@ -86,7 +377,7 @@ func (f *Flattener) getAppliedVersions(ctx context.Context) ([]string, error) {
 		return nil, nil
 	}
 
-	rows, err := f.db.QueryContext(ctx,
+	rows, err := db.QueryContext(ctx,
 		"SELECT version_id FROM goose_db_version WHERE is_applied ORDER BY version_id")
 	if err != nil {
 		return nil, err
@@ -108,28 +399,93 @@ func (f *Flattener) getAppliedVersions(ctx context.Context) ([]string, error) {
 	return versions, rows.Err()
 }
 
-func (f *Flattener) dumpSchema(ctx context.Context) (string, error) {
-	// Use our custom schema dumper, excluding goose tables
-	excludeTables := []string{
-		"goose_db_version",
-		"public.goose_db_version",
+// verifyMigration applies content to a scratch database and checks that the
+// resulting schema matches the source schema
+func (f *Flattener) verifyMigration(ctx context.Context, scratchURL, name string, content []byte, schema string) error {
+	fmt.Fprintln(f.stdout, "Verifying flattened migration against a scratch database...")
+
+	scratch, err := openScratchDB(ctx, scratchURL, f.adminURL)
+	if err != nil {
+		return fmt.Errorf("verifying flattened migration: %w", err)
+	}
+	defer scratch.Close(ctx)
+
+	if err := scratch.applyMigrationFile(ctx, name, content); err != nil {
+		return fmt.Errorf("verifying flattened migration: applying %s: %w (migration files were left unchanged)", name, err)
+	}
+
+	rebuilt, err := f.dumpSchema(ctx, scratch.DB)
+	if err != nil {
+		return fmt.Errorf("verifying flattened migration: dumping scratch schema: %w", err)
+	}
+
+	if diff := schemaDiff(schema, rebuilt); len(diff) > 0 {
+		return schemaDiffError("flattened migration does not reproduce the schema (migration files were left unchanged)", diff)
+	}
+
+	fmt.Fprintln(f.stdout, "Flattened migration reproduces the schema")
+
+	// The Down section must leave nothing behind
+	if err := scratch.rollbackMigrationFile(ctx, name, content); err != nil {
+		return fmt.Errorf("verifying flattened migration: rolling back %s: %w (migration files were left unchanged)", name, err)
+	}
+	remaining, err := f.dumpSchema(ctx, scratch.DB)
+	if err != nil {
+		return fmt.Errorf("verifying flattened migration: dumping scratch schema: %w", err)
+	}
+	if diff := schemaDiff("", remaining); len(diff) > 0 {
+		return schemaDiffError("flattened migration's Down section leaves objects behind (migration files were left unchanged)", diff)
 	}
 
-	schema, err := pgconn.DumpSchema(ctx, f.db, excludeTables)
+	fmt.Fprintln(f.stdout, "Flattened migration's Down section drops the schema")
+	return nil
+}
+
+func (f *Flattener) dumpSchema(ctx context.Context, db *sql.DB) (string, error) {
+	return dumpSchema(ctx, db, f.dumpOpts)
+}
+
+// dumpSchema dumps the schema of db with opts, always excluding goose tables
+func dumpSchema(ctx context.Context, db *sql.DB, opts pgconn.DumpOptions) (string, error) {
+	dump, err := dumpSchemaObjects(ctx, db, opts)
 	if err != nil {
 		return "", err
 	}
 
-	return schema, nil
+	return dump.SQL(), nil
 }
 
-func (f *Flattener) writeInitialMigration(path, schema string) error {
+// dumpSchemaObjects is like dumpSchema but keeps the dumped object list,
+// from which the Down section of the initial migration is built
+func dumpSchemaObjects(ctx context.Context, db *sql.DB, opts pgconn.DumpOptions) (*pgconn.SchemaDump, error) {
+	// Use our custom schema dumper, excluding goose tables
+	opts.ExcludeTables = append([]string{
+		"goose_db_version",
+		"public.goose_db_version",
+	}, opts.ExcludeTables...)
+
+	return pgconn.DumpSchemaObjects(ctx, db, opts)
+}
+
+// renderInitialMigration builds the initial migration from the schema dump
+// and the optional reference data. The Down section drops the dumped objects
+// in reverse order.
+func renderInitialMigration(schema, data, down string) []byte {
 	var buf bytes.Buffer
 
 	buf.WriteString("-- +goose Up\n")
 	buf.WriteString("-- +goose StatementBegin\n")
 	buf.WriteString(schema)
+	if data != "" {
+		buf.WriteString("\n")
+		buf.WriteString(data)
+	}
+	buf.WriteString("\n-- +goose StatementEnd\n")
+
+	buf.WriteString("\n-- +goose Down\n")
+	buf.WriteString("-- +goose StatementBegin\n")
+	buf.WriteString(down)
 	buf.WriteString("\n-- +goose StatementEnd\n")
 
-	return os.WriteFile(path, buf.Bytes(), 0644)
+	return buf.Bytes()
 }
@@ -0,0 +1,19 @@
+package migrate
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestFlattenRejectsReferenceTablesWithUntil(t *testing.T) {
+	f := NewFlattener(nil,
+		WithUntil(20240601000000),
+		WithReferenceTables([]string{"public.countries"}),
+		WithScratchDB("postgres://localhost/app_flatten", ""))
+
+	err := f.Flatten(context.Background(), t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), "--until") {
+		t.Errorf("Flatten() = %v, want an error about --until", err)
+	}
+}
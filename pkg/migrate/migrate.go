@@ -69,6 +69,18 @@ func (m *Migrator) Up(ctx context.Context, dbURL, migrationsDir string) error {
 	return goose.UpContext(ctx, db, migrationsDir)
 }
 
+// UpTo runs pending migrations up to and including version
+func (m *Migrator) UpTo(ctx context.Context, dbURL, migrationsDir string, version int64) error {
+	db, err := m.openDB(dbURL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	m.configureGoose()
+	return goose.UpToContext(ctx, db, migrationsDir, version)
+}
+
 // UpByOne runs a single pending migration
 func (m *Migrator) UpByOne(ctx context.Context, dbURL, migrationsDir string) error {
 	db, err := m.openDB(dbURL)
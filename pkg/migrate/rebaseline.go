@@ -0,0 +1,155 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lucasefe/seedup/pkg/pgconn"
+	"github.com/pressly/goose/v3"
+)
+
+// RebaselineOptions configures Rebaseline
+type RebaselineOptions struct {
+	// ScratchURL is a throwaway database used to rebuild the expected
+	// schema from the migrations directory. It is dropped and recreated.
+	ScratchURL string
+
+	// AdminURL is used to create and drop the scratch database.
+	// Optional; defaults to the current system user on the scratch server.
+	AdminURL string
+
+	// DumpOptions selects the objects compared between the target and the
+	// scratch database. It should match the options used to flatten.
+	DumpOptions pgconn.DumpOptions
+
+	// DryRun verifies the schema and prints the new version history
+	// without changing goose_db_version.
+	DryRun bool
+}
+
+// Rebaseline rewrites goose_db_version in the database at dbURL to match a
+// flattened migrations directory, so an existing environment can adopt a
+// flatten.
+//
+// It first rebuilds the expected schema in a scratch database by running the
+// migrations in migrationsDir up to the newest version applied in the target,
+// and fails if the target's schema differs. It then replaces the target's
+// version history with exactly those migrations, in a single transaction.
+func (m *Migrator) Rebaseline(ctx context.Context, dbURL, migrationsDir string, opts RebaselineOptions) error {
+	target, err := m.openDB(dbURL)
+	if err != nil {
+		return err
+	}
+	defer target.Close()
+
+	if err := checkScratchTarget(ctx, target, opts.ScratchURL); err != nil {
+		return err
+	}
+
+	current, err := getAppliedVersions(ctx, target)
+	if err != nil {
+		return fmt.Errorf("getting applied versions: %w", err)
+	}
+	if len(current) == 0 {
+		return fmt.Errorf("no applied migrations found in target database")
+	}
+	var latestApplied int64
+	if _, err := fmt.Sscan(current[len(current)-1], &latestApplied); err != nil {
+		return fmt.Errorf("parsing applied version %q: %w", current[len(current)-1], err)
+	}
+
+	migrations, err := goose.CollectMigrations(migrationsDir, 0, latestApplied)
+	if err != nil {
+		return fmt.Errorf("collecting migrations: %w", err)
+	}
+	if len(migrations) == 0 {
+		return fmt.Errorf("no migrations in %s at or before applied version %d", migrationsDir, latestApplied)
+	}
+	versions := make([]int64, len(migrations))
+	for i, migration := range migrations {
+		versions[i] = migration.Version
+	}
+
+	if err := m.verifyAgainstMigrations(ctx, target, migrationsDir, latestApplied, opts); err != nil {
+		return err
+	}
+
+	if opts.DryRun {
+		fmt.Fprintf(m.stdout, "Would replace %d goose_db_version entries with versions:\n", len(current))
+		for _, v := range versions {
+			fmt.Fprintf(m.stdout, "  %d\n", v)
+		}
+		return nil
+	}
+
+	if err := rewriteVersionTable(ctx, target, versions); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(m.stdout, "Rebaselined goose_db_version: %d versions replaced by %d\n", len(current), len(versions))
+	return nil
+}
+
+// verifyAgainstMigrations builds the scratch database from the migrations up
+// to version and compares its schema with target
+func (m *Migrator) verifyAgainstMigrations(ctx context.Context, target *sql.DB, migrationsDir string, version int64, opts RebaselineOptions) error {
+	fmt.Fprintln(m.stdout, "Rebuilding schema from migrations in a scratch database...")
+
+	scratch, err := openScratchDB(ctx, opts.ScratchURL, opts.AdminURL)
+	if err != nil {
+		return err
+	}
+	defer scratch.Close(ctx)
+
+	if err := m.UpTo(ctx, scratch.url, migrationsDir, version); err != nil {
+		return fmt.Errorf("running migrations on scratch database: %w", err)
+	}
+
+	expected, err := dumpSchema(ctx, scratch.DB, opts.DumpOptions)
+	if err != nil {
+		return fmt.Errorf("dumping scratch schema: %w", err)
+	}
+	actual, err := dumpSchema(ctx, target, opts.DumpOptions)
+	if err != nil {
+		return fmt.Errorf("dumping target schema: %w", err)
+	}
+
+	if diff := schemaDiff(expected, actual); len(diff) > 0 {
+		return schemaDiffError("target schema does not match the migrations directory (goose_db_version was left unchanged)", diff)
+	}
+
+	fmt.Fprintln(m.stdout, "Target schema matches the migrations directory")
+	return nil
+}
+
+// rewriteVersionTable replaces the contents of goose_db_version with the
+// given versions, keeping goose's version 0 bootstrap row
+func rewriteVersionTable(ctx context.Context, db *sql.DB, versions []int64) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM goose_db_version"); err != nil {
+		return fmt.Errorf("clearing goose_db_version: %w", err)
+	}
+
+	placeholders := make([]string, 0, len(versions)+1)
+	args := make([]any, 0, len(versions)+1)
+	for i, v := range append([]int64{0}, versions...) {
+		placeholders = append(placeholders, fmt.Sprintf("($%d, true)", i+1))
+		args = append(args, v)
+	}
+	query := "INSERT INTO goose_db_version (version_id, is_applied) VALUES " + strings.Join(placeholders, ", ")
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("writing goose_db_version: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lucasefe/seedup/pkg/pgconn"
+)
+
+// referenceTable is a table whose rows are embedded in the initial migration
+type referenceTable struct {
+	schema string
+	name   string
+}
+
+func (t referenceTable) String() string {
+	return t.schema + "." + t.name
+}
+
+// parseReferenceTables parses schema-qualified table names, defaulting
+// unqualified names to the public schema
+func parseReferenceTables(names []string) []referenceTable {
+	var tables []referenceTable
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		t := referenceTable{schema: "public", name: name}
+		if schema, table, ok := strings.Cut(name, "."); ok {
+			t = referenceTable{schema: schema, name: table}
+		}
+		if !seen[t.String()] {
+			seen[t.String()] = true
+			tables = append(tables, t)
+		}
+	}
+	return tables
+}
+
+// dumpReferenceData dumps the rows of tables from db as INSERT statements,
+// ordered so that tables referenced by foreign keys are loaded first. The
+// rows come after the schema's triggers, so user triggers are disabled
+// around each table's INSERT; foreign keys are still checked.
+func dumpReferenceData(ctx context.Context, db *sql.DB, names []string) (string, error) {
+	tables := parseReferenceTables(names)
+	if len(tables) == 0 {
+		return "", nil
+	}
+
+	ordered, err := orderReferenceTables(ctx, db, tables)
+	if err != nil {
+		return "", fmt.Errorf("ordering reference tables: %w", err)
+	}
+
+	var parts []string
+	for _, t := range ordered {
+		data, _, err := pgconn.DumpTableData(ctx, db, t.schema, t.name)
+		if err != nil {
+			return "", fmt.Errorf("dumping reference table %s: %w", t, err)
+		}
+		if data != "" {
+			qualified := pgconn.QuoteIdentifier(t.schema) + "." + pgconn.QuoteIdentifier(t.name)
+			parts = append(parts, fmt.Sprintf("ALTER TABLE %s DISABLE TRIGGER USER;\n%sALTER TABLE %s ENABLE TRIGGER USER;\n",
+				qualified, data, qualified))
+		}
+	}
+
+	if len(parts) == 0 {
+		return "", nil
+	}
+	return "-- Reference data\n" + strings.Join(parts, "\n"), nil
+}
+
+// orderReferenceTables sorts tables so that each comes after the tables it
+// references through foreign keys. References to tables outside the list are
+// ignored, and cycles keep their listed order.
+func orderReferenceTables(ctx context.Context, db *sql.DB, tables []referenceTable) ([]referenceTable, error) {
+	query := `
+		SELECT n.nspname, c.relname, rn.nspname, rc.relname
+		FROM pg_constraint con
+		JOIN pg_class c ON c.oid = con.conrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		JOIN pg_class rc ON rc.oid = con.confrelid
+		JOIN pg_namespace rn ON rn.oid = rc.relnamespace
+		WHERE con.contype = 'f'
+		  AND con.conrelid <> con.confrelid
+	`
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	index := make(map[string]int, len(tables))
+	for i, t := range tables {
+		index[t.String()] = i
+	}
+
+	deps := make(map[int][]int)
+	for rows.Next() {
+		var from, to referenceTable
+		if err := rows.Scan(&from.schema, &from.name, &to.schema, &to.name); err != nil {
+			return nil, err
+		}
+		i, ok := index[from.String()]
+		j, ok2 := index[to.String()]
+		if ok && ok2 {
+			deps[i] = append(deps[i], j)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, d := range deps {
+		sort.Ints(d)
+	}
+
+	// Depth-first visit in listed order, emitting dependencies first
+	state := make([]int, len(tables)) // 0 unvisited, 1 visiting, 2 done
+	var ordered []referenceTable
+	var visit func(i int)
+	visit = func(i int) {
+		if state[i] != 0 {
+			return
+		}
+		state[i] = 1
+		for _, j := range deps[i] {
+			visit(j)
+		}
+		state[i] = 2
+		ordered = append(ordered, tables[i])
+	}
+	for i := range tables {
+		visit(i)
+	}
+	return ordered, nil
+}
@@ -0,0 +1,205 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lucasefe/seedup/pkg/db"
+	"github.com/lucasefe/seedup/pkg/pgconn"
+)
+
+// scratchDB is a throwaway database used to build or check a schema.
+// It is dropped and recreated on open and dropped again on close, so it
+// must not hold anything of value.
+type scratchDB struct {
+	*sql.DB
+	url      string
+	adminURL string
+	manager  *db.Manager
+}
+
+// openScratchDB recreates the database at url and connects to it.
+// adminURL is used to create and drop it; if empty, the default admin URL
+// for the server is used.
+func openScratchDB(ctx context.Context, url, adminURL string) (*scratchDB, error) {
+	if url == "" {
+		return nil, fmt.Errorf("scratch database URL is required")
+	}
+
+	s := &scratchDB{url: url, adminURL: adminURL, manager: db.New()}
+	if err := s.manager.Drop(ctx, url, adminURL); err != nil {
+		return nil, fmt.Errorf("dropping scratch database: %w", err)
+	}
+	if err := s.manager.Create(ctx, url, adminURL); err != nil {
+		return nil, fmt.Errorf("creating scratch database: %w", err)
+	}
+
+	conn, err := pgconn.Open(url)
+	if err != nil {
+		s.manager.Drop(ctx, url, adminURL)
+		return nil, fmt.Errorf("opening scratch database: %w", err)
+	}
+	s.DB = conn
+	return s, nil
+}
+
+// Close closes the connection and drops the scratch database
+func (s *scratchDB) Close(ctx context.Context) error {
+	s.DB.Close()
+	if err := s.manager.Drop(ctx, s.url, s.adminURL); err != nil {
+		return fmt.Errorf("dropping scratch database: %w", err)
+	}
+	return nil
+}
+
+// CheckScratchURL refuses a scratch database URL that points at the database
+// of dbURL, directly or through the "_verify" database derived from it when
+// verifying a flatten from migrations, since scratch databases are dropped
+// and recreated. An empty dbURL is not checked.
+func CheckScratchURL(dbURL, scratchURL string) error {
+	if dbURL == "" || scratchURL == "" {
+		return nil
+	}
+
+	source, err := db.ParseDatabaseURL(dbURL)
+	if err != nil {
+		return fmt.Errorf("parsing database URL: %w", err)
+	}
+	scratch, err := db.ParseDatabaseURL(scratchURL)
+	if err != nil {
+		return fmt.Errorf("parsing scratch database URL: %w", err)
+	}
+
+	if !sameServer(source, scratch) {
+		return nil
+	}
+	for _, name := range []string{scratch.Database, scratch.Database + "_verify"} {
+		if name == source.Database {
+			return fmt.Errorf("scratch database %q is the source database %q; it would be dropped", name, source.Database)
+		}
+	}
+	return nil
+}
+
+// sameServer reports whether two database URLs may point at the same
+// server. Local host names are treated as the same server.
+func sameServer(a, b *db.DBConfig) bool {
+	local := func(host string) bool {
+		return host == "" || host == "localhost" || host == "127.0.0.1" || host == "::1" || strings.HasPrefix(host, "/")
+	}
+	if a.Port != b.Port {
+		return false
+	}
+	return a.Host == b.Host || (local(a.Host) && local(b.Host))
+}
+
+// checkScratchTarget refuses a scratch database that has the same name as
+// the source database, since the scratch database is dropped
+func checkScratchTarget(ctx context.Context, source *sql.DB, scratchURL string) error {
+	if scratchURL == "" {
+		return fmt.Errorf("scratch database URL is required")
+	}
+
+	cfg, err := db.ParseDatabaseURL(scratchURL)
+	if err != nil {
+		return fmt.Errorf("parsing scratch database URL: %w", err)
+	}
+
+	var current string
+	if err := source.QueryRowContext(ctx, "SELECT current_database()").Scan(&current); err != nil {
+		return fmt.Errorf("reading source database name: %w", err)
+	}
+	if cfg.Database == current {
+		return fmt.Errorf("scratch database %q has the same name as the source database; it would be dropped", cfg.Database)
+	}
+	return nil
+}
+
+// applyMigrationFile runs a single migration file against the scratch
+// database, by copying it into an otherwise empty directory for goose
+func (s *scratchDB) applyMigrationFile(ctx context.Context, name string, content []byte) error {
+	return s.runMigrationFile(ctx, name, content, (*Migrator).Up)
+}
+
+// rollbackMigrationFile runs the Down section of a migration file applied
+// with applyMigrationFile
+func (s *scratchDB) rollbackMigrationFile(ctx context.Context, name string, content []byte) error {
+	return s.runMigrationFile(ctx, name, content, (*Migrator).Down)
+}
+
+func (s *scratchDB) runMigrationFile(ctx context.Context, name string, content []byte,
+	run func(*Migrator, context.Context, string, string) error) error {
+	dir, err := os.MkdirTemp("", "seedup-scratch-")
+	if err != nil {
+		return fmt.Errorf("creating temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+		return fmt.Errorf("writing %s: %w", name, err)
+	}
+
+	return run(New(WithStdout(os.Stderr)), ctx, s.url, dir)
+}
+
+// schemaDiff compares two schema dumps line by line and returns the lines
+// missing from actual (prefixed "- ") followed by the extra lines in actual
+// (prefixed "+ "). Blank lines are ignored.
+func schemaDiff(expected, actual string) []string {
+	counts := make(map[string]int)
+	for _, line := range strings.Split(actual, "\n") {
+		counts[line]++
+	}
+
+	var missing []string
+	for _, line := range strings.Split(expected, "\n") {
+		if counts[line] > 0 {
+			counts[line]--
+			continue
+		}
+		if strings.TrimSpace(line) != "" {
+			missing = append(missing, "- "+line)
+		}
+	}
+
+	var extra []string
+	for _, line := range strings.Split(actual, "\n") {
+		if counts[line] > 0 {
+			counts[line]--
+			if strings.TrimSpace(line) != "" {
+				extra = append(extra, "+ "+line)
+			}
+		}
+	}
+
+	return append(missing, extra...)
+}
+
+// scratchURLWithSuffix returns url with suffix appended to its database name
+func scratchURLWithSuffix(url, suffix string) (string, error) {
+	cfg, err := db.ParseDatabaseURL(url)
+	if err != nil {
+		return "", fmt.Errorf("parsing scratch database URL: %w", err)
+	}
+	return cfg.URLWithDatabase(cfg.Database + suffix), nil
+}
+
+// schemaDiffError reports a schema mismatch, showing at most the first 50
+// differing lines
+func schemaDiffError(msg string, diff []string) error {
+	const maxLines = 50
+	shown := diff
+	if len(shown) > maxLines {
+		shown = shown[:maxLines]
+	}
+	msg = fmt.Sprintf("%s, %d differing lines:\n%s", msg, len(diff), strings.Join(shown, "\n"))
+	if len(diff) > maxLines {
+		msg += fmt.Sprintf("\n... and %d more", len(diff)-maxLines)
+	}
+	return errors.New(msg)
+}
@@ -0,0 +1,30 @@
+package migrate
+
+import "testing"
+
+func TestCheckScratchURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		dbURL   string
+		scratch string
+		wantErr bool
+	}{
+		{"different database", "postgres://localhost/app", "postgres://localhost/app_flatten", false},
+		{"same database", "postgres://localhost/app", "postgres://localhost/app", true},
+		{"same database, other local host", "postgres://localhost/app", "postgres://127.0.0.1:5432/app", true},
+		{"verify database is the source", "postgres://localhost/app_verify", "postgres://localhost/app", true},
+		{"same name on another server", "postgres://db1.internal/app", "postgres://db2.internal/app", false},
+		{"same name on another port", "postgres://localhost/app", "postgres://localhost:5433/app", false},
+		{"no database URL", "", "postgres://localhost/app", false},
+		{"no scratch URL", "postgres://localhost/app", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckScratchURL(tt.dbURL, tt.scratch)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckScratchURL(%q, %q) = %v, want error %v", tt.dbURL, tt.scratch, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package pgconn
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// DumpTableData dumps the rows of schema.table as a batched INSERT statement,
+// in the order of OrderByColumns, so repeated dumps are identical. Generated
+// columns are left out. The INSERT is followed by setval calls that move the
+// table's owned sequences (serial and identity) past the inserted values, so
+// rows added later don't collide with them.
+//
+// Returns an empty string and a zero count if the table has no rows.
+func DumpTableData(ctx context.Context, db *sql.DB, schema, table string) (string, int, error) {
+	allColumns, err := GetColumnInfo(ctx, db, schema+"."+table)
+	if err != nil {
+		return "", 0, err
+	}
+	if len(allColumns) == 0 {
+		return "", 0, fmt.Errorf("table %s.%s not found", schema, table)
+	}
+
+	var columns []ColumnInfo
+	var colNames []string
+	for _, col := range allColumns {
+		if !col.IsGenerated {
+			columns = append(columns, col)
+			colNames = append(colNames, QuoteIdentifier(col.Name))
+		}
+	}
+	if len(columns) == 0 {
+		return "", 0, nil
+	}
+
+	qualified := QuoteIdentifier(schema) + "." + QuoteIdentifier(table)
+
+	orderBy, err := OrderByColumns(ctx, db, schema, table, colNames)
+	if err != nil {
+		return "", 0, err
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s ORDER BY %s", strings.Join(colNames, ", "), qualified, orderBy)
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return "", 0, fmt.Errorf("querying %s.%s: %w", schema, table, err)
+	}
+	defer rows.Close()
+
+	var valueRows []string
+	for rows.Next() {
+		values := make([]any, len(columns))
+		valuePtrs := make([]any, len(columns))
+		for i := range values {
+			valuePtrs[i] = &values[i]
+		}
+		if err := rows.Scan(valuePtrs...); err != nil {
+			return "", 0, fmt.Errorf("scanning row: %w", err)
+		}
+
+		serialized := SerializeRow(values, columns)
+		valueRows = append(valueRows, fmt.Sprintf("    (%s)", strings.Join(serialized, ", ")))
+	}
+	if err := rows.Err(); err != nil {
+		return "", 0, fmt.Errorf("iterating rows: %w", err)
+	}
+
+	if len(valueRows) == 0 {
+		return "", 0, nil
+	}
+
+	// OVERRIDING SYSTEM VALUE keeps the ids of GENERATED ALWAYS identity
+	// columns, and is accepted for tables without them
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("-- Table: %s.%s\n", schema, table))
+	sb.WriteString(fmt.Sprintf("INSERT INTO %s (%s) OVERRIDING SYSTEM VALUE VALUES\n",
+		qualified, strings.Join(colNames, ", ")))
+	sb.WriteString(strings.Join(valueRows, ",\n"))
+	sb.WriteString(";\n")
+
+	sequences, err := OwnedSequences(ctx, db, schema, table)
+	if err != nil {
+		return "", 0, fmt.Errorf("reading owned sequences: %w", err)
+	}
+	for _, seq := range sequences {
+		col := QuoteIdentifier(seq.Column)
+		sb.WriteString(fmt.Sprintf("SELECT pg_catalog.setval(%s, COALESCE(MAX(%s), 1), MAX(%s) IS NOT NULL) FROM %s;\n",
+			QuoteString(QuoteIdentifier(seq.Schema)+"."+QuoteIdentifier(seq.Name)), col, col, qualified))
+	}
+
+	return sb.String(), len(valueRows), nil
+}
+
+// OrderByColumns returns an ORDER BY list that gives the rows of
+// schema.table a stable order: its primary key or, for a table without one,
+// the text of every column in colNames (quoted names), since not every type
+// has an ordering operator. Text is compared byte by byte so the order
+// doesn't depend on the collation.
+func OrderByColumns(ctx context.Context, db interface {
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+}, schema, table string, colNames []string) (string, error) {
+	pkColumns, err := PrimaryKeyColumns(ctx, db, schema, table)
+	if err != nil {
+		return "", fmt.Errorf("reading primary key: %w", err)
+	}
+
+	var orderBy []string
+	if len(pkColumns) > 0 {
+		for _, col := range pkColumns {
+			orderBy = append(orderBy, QuoteIdentifier(col))
+		}
+	} else {
+		for _, col := range colNames {
+			orderBy = append(orderBy, fmt.Sprintf(`%s::text COLLATE "C"`, col))
+		}
+	}
+	return strings.Join(orderBy, ", "), nil
+}
+
+// PrimaryKeyColumns returns the primary key columns of schema.table in key
+// order, or nil if it has no primary key.
+func PrimaryKeyColumns(ctx context.Context, db interface {
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+}, schema, table string) ([]string, error) {
+	query := `
+		SELECT a.attname
+		FROM pg_constraint con
+		JOIN pg_class c ON c.oid = con.conrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		CROSS JOIN LATERAL unnest(con.conkey) WITH ORDINALITY AS k(attnum, ord)
+		JOIN pg_attribute a ON a.attrelid = c.oid AND a.attnum = k.attnum
+		WHERE con.contype = 'p'
+		  AND n.nspname = $1
+		  AND c.relname = $2
+		ORDER BY k.ord
+	`
+
+	rows, err := db.QueryContext(ctx, query, schema, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var col string
+		if err := rows.Scan(&col); err != nil {
+			return nil, err
+		}
+		columns = append(columns, col)
+	}
+	return columns, rows.Err()
+}
+
+// OwnedSequence is a serial or identity sequence owned by a table column.
+type OwnedSequence struct {
+	Schema string
+	Name   string
+	Column string
+}
+
+// OwnedSequences returns the serial and identity sequences owned by
+// columns of schema.table.
+func OwnedSequences(ctx context.Context, db interface {
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+}, schema, table string) ([]OwnedSequence, error) {
+	query := `
+		SELECT sn.nspname, s.relname, a.attname
+		FROM pg_depend d
+		JOIN pg_class s ON s.oid = d.objid AND s.relkind = 'S'
+		JOIN pg_namespace sn ON sn.oid = s.relnamespace
+		JOIN pg_class c ON c.oid = d.refobjid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		JOIN pg_attribute a ON a.attrelid = c.oid AND a.attnum = d.refobjsubid
+		WHERE d.classid = 'pg_class'::regclass
+		  AND d.refclassid = 'pg_class'::regclass
+		  AND d.deptype IN ('a', 'i')
+		  AND n.nspname = $1
+		  AND c.relname = $2
+		ORDER BY a.attnum
+	`
+
+	rows, err := db.QueryContext(ctx, query, schema, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sequences []OwnedSequence
+	for rows.Next() {
+		var seq OwnedSequence
+		if err := rows.Scan(&seq.Schema, &seq.Name, &seq.Column); err != nil {
+			return nil, err
+		}
+		sequences = append(sequences, seq)
+	}
+	return sequences, rows.Err()
+}
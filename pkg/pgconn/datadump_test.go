@@ -0,0 +1,31 @@
+package pgconn
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDumpTableDataOrdersTablesWithoutPrimaryKey(t *testing.T) {
+	db := testDB(t)
+	drop := "DROP SCHEMA IF EXISTS seedup_data CASCADE"
+	mustExec(t, db, drop)
+	t.Cleanup(func() { db.Exec(drop) })
+	mustExec(t, db, `
+		CREATE SCHEMA seedup_data;
+		CREATE TABLE seedup_data.tags (name text, weight integer);
+		INSERT INTO seedup_data.tags VALUES ('b', 2), ('a', 9), ('b', 1), ('a', 3);
+	`)
+
+	data, n, err := DumpTableData(context.Background(), db, "seedup_data", "tags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 4 {
+		t.Errorf("dumped %d rows, want 4", n)
+	}
+	want := "    ('a', 3),\n    ('a', 9),\n    ('b', 1),\n    ('b', 2);"
+	if !strings.Contains(data, want) {
+		t.Errorf("rows are not ordered by every column, want:\n%s\ngot:\n%s", want, data)
+	}
+}
@@ -0,0 +1,65 @@
+package pgconn
+
+import (
+	"path"
+)
+
+// dumpFilter decides which schemas and tables DumpSchema includes,
+// based on DumpOptions.
+type dumpFilter struct {
+	schemas        []string
+	excludeSchemas []string
+	includeTables  []string
+	excludeTables  []string
+}
+
+func newDumpFilter(opts DumpOptions) *dumpFilter {
+	return &dumpFilter{
+		schemas:        opts.Schemas,
+		excludeSchemas: opts.ExcludeSchemas,
+		includeTables:  opts.IncludeTables,
+		excludeTables:  opts.ExcludeTables,
+	}
+}
+
+// schema reports whether objects in the given schema should be dumped.
+func (f *dumpFilter) schema(name string) bool {
+	if len(f.schemas) > 0 && !matchAny(f.schemas, name) {
+		return false
+	}
+	return !matchAny(f.excludeSchemas, name)
+}
+
+// table reports whether the given table (or view) should be dumped.
+// Table patterns match either the bare name or the schema-qualified name.
+func (f *dumpFilter) table(schema, name string) bool {
+	if !f.schema(schema) {
+		return false
+	}
+	return MatchTable(f.includeTables, f.excludeTables, schema, name)
+}
+
+// MatchTable reports whether schema.name is selected by the include and
+// exclude glob patterns, in the form of DumpOptions.IncludeTables. An empty
+// include list selects every table that isn't excluded.
+func MatchTable(include, exclude []string, schema, name string) bool {
+	fullName := schema + "." + name
+	if matchAny(exclude, name) || matchAny(exclude, fullName) {
+		return false
+	}
+	if len(include) > 0 {
+		return matchAny(include, name) || matchAny(include, fullName)
+	}
+	return true
+}
+
+// matchAny reports whether name matches any of the glob patterns.
+// Invalid patterns only match the identical string.
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, err := path.Match(p, name); ok || (err != nil && p == name) {
+			return true
+		}
+	}
+	return false
+}
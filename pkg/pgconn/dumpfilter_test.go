@@ -0,0 +1,64 @@
+package pgconn
+
+import "testing"
+
+func TestMatchTable(t *testing.T) {
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		schema  string
+		table   string
+		want    bool
+	}{
+		{name: "no patterns", schema: "public", table: "users", want: true},
+		{name: "bare name", include: []string{"users"}, schema: "app", table: "users", want: true},
+		{name: "bare name other table", include: []string{"users"}, schema: "app", table: "posts", want: false},
+		{name: "qualified name", include: []string{"app.users"}, schema: "app", table: "users", want: true},
+		{name: "qualified name other schema", include: []string{"app.users"}, schema: "public", table: "users", want: false},
+		{name: "glob on name", include: []string{"audit_*"}, schema: "public", table: "audit_log", want: true},
+		{name: "glob on schema", include: []string{"app.*"}, schema: "app", table: "users", want: true},
+		{name: "glob on schema other schema", include: []string{"app.*"}, schema: "billing", table: "users", want: false},
+		{name: "star matches across the dot", include: []string{"app*"}, schema: "app", table: "users", want: true},
+		{name: "character class", include: []string{"log_202[45]"}, schema: "public", table: "log_2025", want: true},
+		{name: "any of several", include: []string{"posts", "users"}, schema: "public", table: "users", want: true},
+		{name: "exclude bare name", exclude: []string{"users"}, schema: "app", table: "users", want: false},
+		{name: "exclude qualified name", exclude: []string{"app.users"}, schema: "public", table: "users", want: true},
+		{name: "exclude glob", exclude: []string{"*_archive"}, schema: "public", table: "orders_archive", want: false},
+		{name: "exclude wins over include", include: []string{"app.*"}, exclude: []string{"app.secrets"}, schema: "app", table: "secrets", want: false},
+		{name: "invalid pattern matches itself", include: []string{"weird["}, schema: "public", table: "weird[", want: true},
+		{name: "invalid pattern matches nothing else", include: []string{"weird["}, schema: "public", table: "weird", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchTable(tt.include, tt.exclude, tt.schema, tt.table); got != tt.want {
+				t.Errorf("MatchTable(%q, %q, %q, %q) = %v, want %v", tt.include, tt.exclude, tt.schema, tt.table, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDumpFilterSchema(t *testing.T) {
+	f := newDumpFilter(DumpOptions{
+		Schemas:        []string{"app*"},
+		ExcludeSchemas: []string{"app_archive"},
+		IncludeTables:  []string{"users"},
+	})
+
+	tests := []struct {
+		schema, table string
+		want          bool
+	}{
+		{"app", "users", true},
+		{"app_v2", "users", true},
+		{"app_archive", "users", false},
+		{"public", "users", false},
+		{"app", "posts", false},
+	}
+	for _, tt := range tests {
+		if got := f.table(tt.schema, tt.table); got != tt.want {
+			t.Errorf("table(%q, %q) = %v, want %v", tt.schema, tt.table, got, tt.want)
+		}
+	}
+}
@@ -5,200 +5,328 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+
+	"github.com/lib/pq"
 )
 
+// DumpOptions configures how the schema is dumped.
+type DumpOptions struct {
+	// Schemas limits the dump to these schemas. Empty means all
+	// non-system schemas.
+	Schemas []string
+
+	// ExcludeSchemas leaves these schemas out of the dump.
+	ExcludeSchemas []string
+
+	// IncludeTables limits tables, views and their dependent objects
+	// (constraints, indexes, triggers, owned sequences) to those matching
+	// one of these glob patterns. Patterns match either the bare name
+	// ("orders_*") or the schema-qualified name ("billing.*"). Empty means
+	// all tables.
+	IncludeTables []string
+
+	// ExcludeTables lists tables to leave out of the dump, as glob patterns
+	// in the same form as IncludeTables.
+	ExcludeTables []string
+
+	// NoFunctions skips functions and procedures, along with the operators,
+	// aggregates and casts built on them. Triggers call functions, so this
+	// is usually combined with NoTriggers.
+	NoFunctions bool
+
+	// NoTriggers skips triggers and event triggers.
+	NoTriggers bool
+
+	// NoExtensions skips CREATE EXTENSION statements.
+	NoExtensions bool
+
+	// MaterializedViewsNoData creates every materialized view WITH NO DATA,
+	// even if it is populated in the source database. Populating large
+	// materialized views in a fresh database can be expensive.
+	MaterializedViewsNoData bool
+
+	// SequenceValues appends setval calls that carry over each sequence's
+	// current value, so ids generated after the migration runs don't collide
+	// with ids that appear in seed data.
+	SequenceValues bool
+
+	// Tablespaces keeps the TABLESPACE clause of tables stored outside the
+	// default tablespace. It is off by default because tablespaces rarely
+	// exist on development machines.
+	Tablespaces bool
+}
+
 // DumpSchema dumps the database schema to SQL DDL statements.
 // It returns SQL that can recreate the schema (excluding data).
 func DumpSchema(ctx context.Context, db *sql.DB, excludeTables []string) (string, error) {
-	var parts []string
+	return DumpSchemaWithOptions(ctx, db, DumpOptions{ExcludeTables: excludeTables})
+}
 
-	// Build set of excluded tables for quick lookup
-	excludeSet := make(map[string]bool)
-	for _, t := range excludeTables {
-		excludeSet[t] = true
+// DumpSchemaWithOptions dumps the database schema to SQL DDL statements
+// using the given options.
+func DumpSchemaWithOptions(ctx context.Context, db *sql.DB, opts DumpOptions) (string, error) {
+	dump, err := DumpSchemaObjects(ctx, db, opts)
+	if err != nil {
+		return "", err
 	}
+	return dump.SQL(), nil
+}
+
+// DumpSchemaObjects dumps the database schema like [DumpSchemaWithOptions],
+// keeping the list of dumped objects so the dump can also be reversed with
+// [SchemaDump.DropSQL].
+func DumpSchemaObjects(ctx context.Context, db *sql.DB, opts DumpOptions) (*SchemaDump, error) {
+	dump := &SchemaDump{}
+
+	filter := newDumpFilter(opts)
 
 	// 1. Dump schemas (non-system)
-	schemas, err := dumpSchemas(ctx, db)
+	schemas, err := dumpSchemas(ctx, db, filter)
 	if err != nil {
-		return "", fmt.Errorf("dumping schemas: %w", err)
-	}
-	if len(schemas) > 0 {
-		parts = append(parts, "-- Schemas")
-		parts = append(parts, schemas...)
-		parts = append(parts, "")
+		return nil, fmt.Errorf("dumping schemas: %w", err)
 	}
+	dump.add("Schemas", schemas)
 
 	// 2. Dump extensions
-	extensions, err := dumpExtensions(ctx, db)
-	if err != nil {
-		return "", fmt.Errorf("dumping extensions: %w", err)
-	}
-	if len(extensions) > 0 {
-		parts = append(parts, "-- Extensions")
-		parts = append(parts, extensions...)
-		parts = append(parts, "")
+	if !opts.NoExtensions {
+		extensions, err := dumpExtensions(ctx, db, filter)
+		if err != nil {
+			return nil, fmt.Errorf("dumping extensions: %w", err)
+		}
+		dump.add("Extensions", extensions)
 	}
 
-	// 3. Dump enum types
-	enums, err := dumpEnums(ctx, db)
+	// 3. Dump foreign data wrappers, servers and user mappings (after the
+	// extensions that usually provide the wrappers)
+	scope, err := foreignServersInScope(ctx, db, filter)
 	if err != nil {
-		return "", fmt.Errorf("dumping enums: %w", err)
+		return nil, fmt.Errorf("listing foreign servers: %w", err)
 	}
-	if len(enums) > 0 {
-		parts = append(parts, "-- Enum types")
-		parts = append(parts, enums...)
-		parts = append(parts, "")
+
+	wrappers, err := dumpForeignDataWrappers(ctx, db, scope)
+	if err != nil {
+		return nil, fmt.Errorf("dumping foreign data wrappers: %w", err)
 	}
+	dump.add("Foreign data wrappers", wrappers)
 
-	// 4. Dump domain types
-	domains, err := dumpDomains(ctx, db)
+	servers, templated, err := dumpForeignServers(ctx, db, scope)
 	if err != nil {
-		return "", fmt.Errorf("dumping domains: %w", err)
+		return nil, fmt.Errorf("dumping foreign servers: %w", err)
 	}
-	if len(domains) > 0 {
-		parts = append(parts, "-- Domain types")
-		parts = append(parts, domains...)
-		parts = append(parts, "")
+	if templated {
+		dump.addEnvsub("Foreign servers", servers)
+	} else {
+		dump.add("Foreign servers", servers)
 	}
 
-	// 5. Dump composite types
-	composites, err := dumpCompositeTypes(ctx, db)
+	mappings, err := dumpUserMappings(ctx, db, scope)
 	if err != nil {
-		return "", fmt.Errorf("dumping composite types: %w", err)
+		return nil, fmt.Errorf("dumping user mappings: %w", err)
 	}
-	if len(composites) > 0 {
-		parts = append(parts, "-- Composite types")
-		parts = append(parts, composites...)
-		parts = append(parts, "")
+	// Credentials are templated as environment variables, which goose
+	// substitutes only inside an ENVSUB block
+	dump.addEnvsub("User mappings", mappings)
+
+	// 4. Dump collations (before types and tables that use them)
+	collations, err := dumpCollations(ctx, db, filter)
+	if err != nil {
+		return nil, fmt.Errorf("dumping collations: %w", err)
 	}
+	dump.add("Collations", collations)
 
-	// 6. Dump sequences
-	sequences, err := dumpSequences(ctx, db, excludeSet)
+	// 5. Dump enum types
+	enums, err := dumpEnums(ctx, db, filter)
 	if err != nil {
-		return "", fmt.Errorf("dumping sequences: %w", err)
+		return nil, fmt.Errorf("dumping enums: %w", err)
 	}
-	if len(sequences) > 0 {
-		parts = append(parts, "-- Sequences")
-		parts = append(parts, sequences...)
-		parts = append(parts, "")
+	dump.add("Enum types", enums)
+
+	// 6. Dump domain types
+	domains, err := dumpDomains(ctx, db, filter)
+	if err != nil {
+		return nil, fmt.Errorf("dumping domains: %w", err)
 	}
+	dump.add("Domain types", domains)
 
-	// 7. Dump PL/pgSQL functions (before tables, since table defaults may reference them)
-	// These don't validate table references at creation time.
-	functionsEarly, err := dumpFunctionsEarly(ctx, db)
+	// 7. Dump range types
+	ranges, err := dumpRangeTypes(ctx, db, filter)
 	if err != nil {
-		return "", fmt.Errorf("dumping early functions: %w", err)
+		return nil, fmt.Errorf("dumping range types: %w", err)
 	}
-	if len(functionsEarly) > 0 {
-		parts = append(parts, "-- Functions (PL/pgSQL)")
-		parts = append(parts, functionsEarly...)
-		parts = append(parts, "")
+	dump.add("Range types", ranges)
+
+	// 8. Dump composite types
+	composites, err := dumpCompositeTypes(ctx, db, filter)
+	if err != nil {
+		return nil, fmt.Errorf("dumping composite types: %w", err)
 	}
+	dump.add("Composite types", composites)
 
-	// 8. Dump tables (after PL/pgSQL functions, before SQL functions)
-	tables, err := dumpTables(ctx, db, excludeSet)
+	// 9. Dump sequences
+	sequences, err := dumpSequences(ctx, db, filter)
 	if err != nil {
-		return "", fmt.Errorf("dumping tables: %w", err)
+		return nil, fmt.Errorf("dumping sequences: %w", err)
 	}
-	if len(tables) > 0 {
-		parts = append(parts, "-- Tables")
-		parts = append(parts, tables...)
-		parts = append(parts, "")
+	dump.add("Sequences", sequences)
+
+	// 10. Dump PL/pgSQL functions (before tables, since table defaults may reference them)
+	if !opts.NoFunctions {
+		// These don't validate table references at creation time.
+		functionsEarly, err := dumpFunctionsEarly(ctx, db, filter)
+		if err != nil {
+			return nil, fmt.Errorf("dumping early functions: %w", err)
+		}
+		dump.add("Functions (PL/pgSQL)", functionsEarly)
 	}
 
-	// 9. Dump SQL functions (after tables, since they validate table references at creation time)
-	functionsLate, err := dumpFunctionsLate(ctx, db)
+	// 11. Dump tables (after PL/pgSQL functions, before SQL functions)
+	tables, err := dumpTables(ctx, db, filter, opts.Tablespaces)
 	if err != nil {
-		return "", fmt.Errorf("dumping late functions: %w", err)
+		return nil, fmt.Errorf("dumping tables: %w", err)
 	}
-	if len(functionsLate) > 0 {
-		parts = append(parts, "-- Functions (SQL)")
-		parts = append(parts, functionsLate...)
-		parts = append(parts, "")
+	dump.add("Tables", tables)
+
+	// 12. Dump foreign tables
+	foreignTables, err := dumpForeignTables(ctx, db, filter)
+	if err != nil {
+		return nil, fmt.Errorf("dumping foreign tables: %w", err)
 	}
+	dump.add("Foreign tables", foreignTables)
 
-	// 10. Dump views
-	views, err := dumpViews(ctx, db, excludeSet)
+	// 13. Attach serial sequences to their columns
+	ownership, err := dumpSequenceOwnership(ctx, db, filter)
 	if err != nil {
-		return "", fmt.Errorf("dumping views: %w", err)
+		return nil, fmt.Errorf("dumping sequence ownership: %w", err)
+	}
+	dump.add("Sequence ownership", statements(ownership))
+
+	// 14. Dump SQL functions (after tables, since they validate table references at creation time)
+	if !opts.NoFunctions {
+		functionsLate, err := dumpFunctionsLate(ctx, db, filter)
+		if err != nil {
+			return nil, fmt.Errorf("dumping late functions: %w", err)
+		}
+		dump.add("Functions (SQL)", functionsLate)
 	}
-	if len(views) > 0 {
-		parts = append(parts, "-- Views")
-		parts = append(parts, views...)
-		parts = append(parts, "")
+
+	// 15. Dump operators, aggregates and casts (after the functions they
+	// are built on, before views that may use them)
+	if !opts.NoFunctions {
+		operators, err := dumpOperators(ctx, db, filter)
+		if err != nil {
+			return nil, fmt.Errorf("dumping operators: %w", err)
+		}
+		dump.add("Operators", operators)
+
+		aggregates, err := dumpAggregates(ctx, db, filter)
+		if err != nil {
+			return nil, fmt.Errorf("dumping aggregates: %w", err)
+		}
+		dump.add("Aggregates", aggregates)
+
+		casts, err := dumpCasts(ctx, db, filter)
+		if err != nil {
+			return nil, fmt.Errorf("dumping casts: %w", err)
+		}
+		dump.add("Casts", casts)
 	}
 
-	// 11. Dump primary keys
-	pks, err := dumpPrimaryKeys(ctx, db, excludeSet)
+	// 16. Dump views and materialized views (in dependency order, since
+	// either kind can select from the other)
+	views, err := dumpViews(ctx, db, filter, opts.MaterializedViewsNoData)
 	if err != nil {
-		return "", fmt.Errorf("dumping primary keys: %w", err)
+		return nil, fmt.Errorf("dumping views: %w", err)
 	}
-	if len(pks) > 0 {
-		parts = append(parts, "-- Primary keys")
-		parts = append(parts, pks...)
-		parts = append(parts, "")
+	dump.add("Views", views)
+
+	// 17. Dump primary keys
+	pks, err := dumpPrimaryKeys(ctx, db, filter)
+	if err != nil {
+		return nil, fmt.Errorf("dumping primary keys: %w", err)
 	}
+	dump.add("Primary keys", pks)
 
-	// 12. Dump unique constraints
-	uniques, err := dumpUniqueConstraints(ctx, db, excludeSet)
+	// 18. Dump unique constraints
+	uniques, err := dumpUniqueConstraints(ctx, db, filter)
 	if err != nil {
-		return "", fmt.Errorf("dumping unique constraints: %w", err)
+		return nil, fmt.Errorf("dumping unique constraints: %w", err)
 	}
-	if len(uniques) > 0 {
-		parts = append(parts, "-- Unique constraints")
-		parts = append(parts, uniques...)
-		parts = append(parts, "")
+	dump.add("Unique constraints", uniques)
+
+	// 19. Dump check constraints
+	checks, err := dumpCheckConstraints(ctx, db, filter)
+	if err != nil {
+		return nil, fmt.Errorf("dumping check constraints: %w", err)
 	}
+	dump.add("Check constraints", checks)
 
-	// 13. Dump check constraints
-	checks, err := dumpCheckConstraints(ctx, db, excludeSet)
+	// 20. Dump exclusion constraints
+	exclusions, err := dumpExclusionConstraints(ctx, db, filter)
 	if err != nil {
-		return "", fmt.Errorf("dumping check constraints: %w", err)
+		return nil, fmt.Errorf("dumping exclusion constraints: %w", err)
 	}
-	if len(checks) > 0 {
-		parts = append(parts, "-- Check constraints")
-		parts = append(parts, checks...)
-		parts = append(parts, "")
+	dump.add("Exclusion constraints", exclusions)
+
+	// 21. Dump foreign keys (after all tables and PKs are created)
+	fks, err := dumpForeignKeys(ctx, db, filter)
+	if err != nil {
+		return nil, fmt.Errorf("dumping foreign keys: %w", err)
 	}
+	dump.add("Foreign keys", fks)
 
-	// 14. Dump foreign keys (after all tables and PKs are created)
-	fks, err := dumpForeignKeys(ctx, db, excludeSet)
+	// 22. Dump indexes (non-constraint indexes)
+	indexes, err := dumpIndexes(ctx, db, filter)
 	if err != nil {
-		return "", fmt.Errorf("dumping foreign keys: %w", err)
+		return nil, fmt.Errorf("dumping indexes: %w", err)
 	}
-	if len(fks) > 0 {
-		parts = append(parts, "-- Foreign keys")
-		parts = append(parts, fks...)
-		parts = append(parts, "")
+	dump.add("Indexes", indexes)
+
+	// 23. Dump replica identity (after indexes, which USING INDEX refers to)
+	replicaIdentity, err := dumpReplicaIdentity(ctx, db, filter)
+	if err != nil {
+		return nil, fmt.Errorf("dumping replica identity: %w", err)
 	}
+	dump.add("Replica identity", statements(replicaIdentity))
 
-	// 15. Dump indexes (non-constraint indexes)
-	indexes, err := dumpIndexes(ctx, db, excludeSet)
+	// 24. Dump publications (after replica identity, which published
+	// tables need for updates and deletes)
+	publications, err := dumpPublications(ctx, db, filter)
 	if err != nil {
-		return "", fmt.Errorf("dumping indexes: %w", err)
+		return nil, fmt.Errorf("dumping publications: %w", err)
 	}
-	if len(indexes) > 0 {
-		parts = append(parts, "-- Indexes")
-		parts = append(parts, indexes...)
-		parts = append(parts, "")
+	dump.add("Publications", publications)
+
+	// 25. Dump triggers (after functions and tables)
+	if !opts.NoTriggers {
+		triggers, err := dumpTriggers(ctx, db, filter)
+		if err != nil {
+			return nil, fmt.Errorf("dumping triggers: %w", err)
+		}
+		dump.add("Triggers", triggers)
 	}
 
-	// 16. Dump triggers (after functions and tables)
-	triggers, err := dumpTriggers(ctx, db, excludeSet)
-	if err != nil {
-		return "", fmt.Errorf("dumping triggers: %w", err)
+	// 26. Dump event triggers
+	if !opts.NoTriggers {
+		eventTriggers, err := dumpEventTriggers(ctx, db, filter)
+		if err != nil {
+			return nil, fmt.Errorf("dumping event triggers: %w", err)
+		}
+		dump.add("Event triggers", eventTriggers)
 	}
-	if len(triggers) > 0 {
-		parts = append(parts, "-- Triggers")
-		parts = append(parts, triggers...)
-		parts = append(parts, "")
+
+	// 27. Dump sequence values (opt-in)
+	if opts.SequenceValues {
+		values, err := dumpSequenceValues(ctx, db, filter)
+		if err != nil {
+			return nil, fmt.Errorf("dumping sequence values: %w", err)
+		}
+		dump.add("Sequence values", statements(values))
 	}
 
-	return strings.Join(parts, "\n"), nil
+	return dump, nil
 }
 
-func dumpSchemas(ctx context.Context, db *sql.DB) ([]string, error) {
+func dumpSchemas(ctx context.Context, db *sql.DB, filter *dumpFilter) ([]SchemaObject, error) {
 	query := `
 		SELECT nspname
 		FROM pg_namespace
@@ -215,19 +343,25 @@ func dumpSchemas(ctx context.Context, db *sql.DB) ([]string, error) {
 	}
 	defer rows.Close()
 
-	var results []string
+	var results []SchemaObject
 	for rows.Next() {
 		var name string
 		if err := rows.Scan(&name); err != nil {
 			return nil, err
 		}
-		results = append(results, fmt.Sprintf("CREATE SCHEMA %s;", QuoteIdentifier(name)))
+		if !filter.schema(name) {
+			continue
+		}
+		results = append(results, SchemaObject{
+			SQL:  fmt.Sprintf("CREATE SCHEMA %s;", QuoteIdentifier(name)),
+			Drop: fmt.Sprintf("DROP SCHEMA IF EXISTS %s;", QuoteIdentifier(name)),
+		})
 	}
 
 	return results, rows.Err()
 }
 
-func dumpExtensions(ctx context.Context, db *sql.DB) ([]string, error) {
+func dumpExtensions(ctx context.Context, db *sql.DB, filter *dumpFilter) ([]SchemaObject, error) {
 	query := `
 		SELECT extname, n.nspname
 		FROM pg_extension e
@@ -242,20 +376,28 @@ func dumpExtensions(ctx context.Context, db *sql.DB) ([]string, error) {
 	}
 	defer rows.Close()
 
-	var results []string
+	var results []SchemaObject
 	for rows.Next() {
 		var name, schema string
 		if err := rows.Scan(&name, &schema); err != nil {
 			return nil, err
 		}
-		results = append(results, fmt.Sprintf("CREATE EXTENSION IF NOT EXISTS %s WITH SCHEMA %s;",
-			QuoteIdentifier(name), QuoteIdentifier(schema)))
+		// Extensions are database-wide, but one installed into a schema that
+		// is not dumped cannot be created (public always exists)
+		if schema != "public" && !filter.schema(schema) {
+			continue
+		}
+		results = append(results, SchemaObject{
+			SQL: fmt.Sprintf("CREATE EXTENSION IF NOT EXISTS %s WITH SCHEMA %s;",
+				QuoteIdentifier(name), QuoteIdentifier(schema)),
+			Drop: fmt.Sprintf("DROP EXTENSION IF EXISTS %s;", QuoteIdentifier(name)),
+		})
 	}
 
 	return results, rows.Err()
 }
 
-func dumpEnums(ctx context.Context, db *sql.DB) ([]string, error) {
+func dumpEnums(ctx context.Context, db *sql.DB, filter *dumpFilter) ([]SchemaObject, error) {
 	query := `
 		SELECT n.nspname as schema, t.typname as name,
 		       array_agg(e.enumlabel ORDER BY e.enumsortorder) as labels
@@ -276,7 +418,7 @@ func dumpEnums(ctx context.Context, db *sql.DB) ([]string, error) {
 	}
 	defer rows.Close()
 
-	var results []string
+	var results []SchemaObject
 	for rows.Next() {
 		var schema, name string
 		var labels []string
@@ -292,6 +434,10 @@ func dumpEnums(ctx context.Context, db *sql.DB) ([]string, error) {
 			}
 		}
 
+		if !filter.schema(schema) {
+			continue
+		}
+
 		quotedLabels := make([]string, len(labels))
 		for i, l := range labels {
 			quotedLabels[i] = QuoteString(l)
@@ -301,13 +447,13 @@ func dumpEnums(ctx context.Context, db *sql.DB) ([]string, error) {
 			QuoteIdentifier(schema),
 			QuoteIdentifier(name),
 			strings.Join(quotedLabels, ",\n    "))
-		results = append(results, sql)
+		results = append(results, SchemaObject{SQL: sql, Drop: dropStatement("TYPE", schema, name)})
 	}
 
 	return results, rows.Err()
 }
 
-func dumpDomains(ctx context.Context, db *sql.DB) ([]string, error) {
+func dumpDomains(ctx context.Context, db *sql.DB, filter *dumpFilter) ([]SchemaObject, error) {
 	// Query domain metadata without array_agg to avoid PostgreSQL array escaping issues
 	domainsQuery := `
 		SELECT n.nspname as schema,
@@ -338,7 +484,7 @@ func dumpDomains(ctx context.Context, db *sql.DB) ([]string, error) {
 		WHERE c.contypid = $1
 	`
 
-	var results []string
+	var results []SchemaObject
 	for rows.Next() {
 		var schema, name, baseType string
 		var notNull bool
@@ -349,6 +495,10 @@ func dumpDomains(ctx context.Context, db *sql.DB) ([]string, error) {
 			return nil, err
 		}
 
+		if !filter.schema(schema) {
+			continue
+		}
+
 		sql := fmt.Sprintf("CREATE DOMAIN %s.%s AS %s",
 			QuoteIdentifier(schema),
 			QuoteIdentifier(name),
@@ -382,13 +532,13 @@ func dumpDomains(ctx context.Context, db *sql.DB) ([]string, error) {
 		}
 
 		sql += ";"
-		results = append(results, sql)
+		results = append(results, SchemaObject{SQL: sql, Drop: dropStatement("DOMAIN", schema, name)})
 	}
 
 	return results, rows.Err()
 }
 
-func dumpCompositeTypes(ctx context.Context, db *sql.DB) ([]string, error) {
+func dumpCompositeTypes(ctx context.Context, db *sql.DB, filter *dumpFilter) ([]SchemaObject, error) {
 	// Get composite types, excluding auto-generated types for tables and views
 	query := `
 		SELECT n.nspname as schema, t.typname as name
@@ -398,7 +548,7 @@ func dumpCompositeTypes(ctx context.Context, db *sql.DB) ([]string, error) {
 		  AND n.nspname NOT IN ('pg_catalog', 'information_schema')
 		  AND n.nspname NOT LIKE 'pg_temp_%'
 		  AND n.nspname NOT LIKE 'pg_toast_temp_%'
-		  AND NOT EXISTS (SELECT 1 FROM pg_class c WHERE c.reltype = t.oid AND c.relkind IN ('r', 'v'))
+		  AND NOT EXISTS (SELECT 1 FROM pg_class c WHERE c.reltype = t.oid AND c.relkind <> 'c')
 		ORDER BY n.nspname, t.typname
 	`
 
@@ -408,13 +558,17 @@ func dumpCompositeTypes(ctx context.Context, db *sql.DB) ([]string, error) {
 	}
 	defer rows.Close()
 
-	var results []string
+	var results []SchemaObject
 	for rows.Next() {
 		var schema, name string
 		if err := rows.Scan(&schema, &name); err != nil {
 			return nil, err
 		}
 
+		if !filter.schema(schema) {
+			continue
+		}
+
 		// Get attributes for this composite type
 		attrQuery := `
 			SELECT a.attname, pg_catalog.format_type(a.atttypid, a.atttypmod)
@@ -447,23 +601,45 @@ func dumpCompositeTypes(ctx context.Context, db *sql.DB) ([]string, error) {
 				QuoteIdentifier(schema),
 				QuoteIdentifier(name),
 				strings.Join(attrs, ",\n"))
-			results = append(results, sql)
+			results = append(results, SchemaObject{SQL: sql, Drop: dropStatement("TYPE", schema, name)})
 		}
 	}
 
 	return results, rows.Err()
 }
 
-func dumpSequences(ctx context.Context, db *sql.DB, _ map[string]bool) ([]string, error) {
-	query := `
-		SELECT schemaname, sequencename, start_value, increment_by, max_value, min_value, cache_size, cycle
-		FROM pg_sequences
-		WHERE schemaname NOT IN ('pg_catalog', 'information_schema')
-		  AND schemaname NOT LIKE 'pg_temp_%'
-		  AND schemaname NOT LIKE 'pg_toast_temp_%'
-		  AND sequencename NOT LIKE 'goose_%'
-		ORDER BY schemaname, sequencename
-	`
+func dumpCollations(ctx context.Context, db *sql.DB, filter *dumpFilter) ([]SchemaObject, error) {
+	version, err := serverVersionNum(ctx, db)
+	if err != nil {
+		return nil, fmt.Errorf("reading server version: %w", err)
+	}
+
+	// The ICU locale moved out of collcollate in PostgreSQL 15 and was
+	// renamed to colllocale in 17
+	localeExpr := "c.collcollate::text"
+	switch {
+	case version >= 170000:
+		localeExpr = "COALESCE(c.colllocale, c.collcollate)::text"
+	case version >= 150000:
+		localeExpr = "COALESCE(c.colliculocale, c.collcollate)::text"
+	}
+
+	query := fmt.Sprintf(`
+		SELECT n.nspname, c.collname, c.collprovider::text, c.collisdeterministic,
+		       %s, c.collctype::text
+		FROM pg_collation c
+		JOIN pg_namespace n ON c.collnamespace = n.oid
+		WHERE n.nspname NOT IN ('pg_catalog', 'information_schema')
+		  AND n.nspname NOT LIKE 'pg_temp_%%'
+		  AND n.nspname NOT LIKE 'pg_toast_temp_%%'
+		  AND NOT EXISTS (
+		      SELECT 1 FROM pg_depend d
+		      WHERE d.classid = 'pg_collation'::regclass
+		        AND d.objid = c.oid
+		        AND d.deptype = 'e'
+		  )
+		ORDER BY n.nspname, c.collname
+	`, localeExpr)
 
 	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
@@ -471,53 +647,335 @@ func dumpSequences(ctx context.Context, db *sql.DB, _ map[string]bool) ([]string
 	}
 	defer rows.Close()
 
-	var results []string
+	var results []SchemaObject
 	for rows.Next() {
-		var schema, name string
-		var startVal, incBy, maxVal, minVal, cacheSize sql.NullInt64
-		var cycle sql.NullBool
+		var schema, name, provider string
+		var deterministic bool
+		var locale, ctype sql.NullString
+		if err := rows.Scan(&schema, &name, &provider, &deterministic, &locale, &ctype); err != nil {
+			return nil, err
+		}
 
-		if err := rows.Scan(&schema, &name, &startVal, &incBy, &maxVal, &minVal, &cacheSize, &cycle); err != nil {
-			return nil, fmt.Errorf("scanning sequence: %w", err)
+		if !filter.schema(schema) {
+			continue
 		}
 
-		sql := fmt.Sprintf("CREATE SEQUENCE %s.%s",
-			QuoteIdentifier(schema),
-			QuoteIdentifier(name))
+		var opts []string
+		switch provider {
+		case "i":
+			opts = append(opts, "provider = icu", "locale = "+QuoteString(locale.String))
+		case "b":
+			opts = append(opts, "provider = builtin", "locale = "+QuoteString(locale.String))
+		case "c":
+			opts = append(opts, "provider = libc")
+			if locale.String == ctype.String {
+				opts = append(opts, "locale = "+QuoteString(locale.String))
+			} else {
+				opts = append(opts,
+					"lc_collate = "+QuoteString(locale.String),
+					"lc_ctype = "+QuoteString(ctype.String))
+			}
+		default:
+			// The database default collation cannot be recreated
+			continue
+		}
+		if !deterministic {
+			opts = append(opts, "deterministic = false")
+		}
+
+		results = append(results, SchemaObject{
+			SQL: fmt.Sprintf("CREATE COLLATION %s.%s (%s);",
+				QuoteIdentifier(schema),
+				QuoteIdentifier(name),
+				strings.Join(opts, ", ")),
+			Drop: dropStatement("COLLATION", schema, name),
+		})
+	}
+
+	return results, rows.Err()
+}
+
+// dumpRangeTypes dumps CREATE TYPE ... AS RANGE statements.
+// CANONICAL functions are not dumped: they take the range type itself as an
+// argument and must be written in C, so they cannot be recreated here.
+func dumpRangeTypes(ctx context.Context, db *sql.DB, filter *dumpFilter) ([]SchemaObject, error) {
+	version, err := serverVersionNum(ctx, db)
+	if err != nil {
+		return nil, fmt.Errorf("reading server version: %w", err)
+	}
+
+	// Multirange types were added in PostgreSQL 14
+	multirangeExpr := "NULL::text"
+	if version >= 140000 {
+		multirangeExpr = "format_type(r.rngmultitypid, NULL)"
+	}
+
+	tx, err := beginCatalogTx(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf(`
+		SELECT n.nspname, t.typname,
+		       format_type(r.rngsubtype, NULL),
+		       CASE WHEN NOT opc.opcdefault
+		            THEN quote_ident(opcn.nspname) || '.' || quote_ident(opc.opcname)
+		       END,
+		       CASE WHEN r.rngcollation <> 0 AND r.rngcollation <> st.typcollation
+		            THEN quote_ident(cn.nspname) || '.' || quote_ident(co.collname)
+		       END,
+		       CASE WHEN r.rngsubdiff::oid <> 0 THEN r.rngsubdiff::text END,
+		       %s
+		FROM pg_range r
+		JOIN pg_type t ON t.oid = r.rngtypid
+		JOIN pg_namespace n ON n.oid = t.typnamespace
+		JOIN pg_type st ON st.oid = r.rngsubtype
+		JOIN pg_opclass opc ON opc.oid = r.rngsubopc
+		JOIN pg_namespace opcn ON opcn.oid = opc.opcnamespace
+		LEFT JOIN pg_collation co ON co.oid = r.rngcollation
+		LEFT JOIN pg_namespace cn ON cn.oid = co.collnamespace
+		WHERE n.nspname NOT IN ('pg_catalog', 'information_schema')
+		  AND n.nspname NOT LIKE 'pg_temp_%%'
+		  AND n.nspname NOT LIKE 'pg_toast_temp_%%'
+		  AND NOT EXISTS (
+		      SELECT 1 FROM pg_depend d
+		      WHERE d.classid = 'pg_type'::regclass
+		        AND d.objid = t.oid
+		        AND d.deptype = 'e'
+		  )
+		ORDER BY n.nspname, t.typname
+	`, multirangeExpr)
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []SchemaObject
+	for rows.Next() {
+		var schema, name, subtype string
+		var opclass, collation, subdiff, multirange sql.NullString
+		if err := rows.Scan(&schema, &name, &subtype, &opclass, &collation, &subdiff, &multirange); err != nil {
+			return nil, err
+		}
+
+		if !filter.schema(schema) {
+			continue
+		}
 
-		if startVal.Valid {
-			sql += fmt.Sprintf(" START WITH %d", startVal.Int64)
+		opts := []string{"SUBTYPE = " + subtype}
+		if opclass.Valid {
+			opts = append(opts, "SUBTYPE_OPCLASS = "+opclass.String)
+		}
+		if collation.Valid {
+			opts = append(opts, "COLLATION = "+collation.String)
 		}
-		if incBy.Valid {
-			sql += fmt.Sprintf(" INCREMENT BY %d", incBy.Int64)
+		if subdiff.Valid {
+			opts = append(opts, "SUBTYPE_DIFF = "+subdiff.String)
 		}
-		if minVal.Valid {
-			sql += fmt.Sprintf(" MINVALUE %d", minVal.Int64)
+		if multirange.Valid {
+			opts = append(opts, "MULTIRANGE_TYPE_NAME = "+multirange.String)
 		}
-		if maxVal.Valid {
-			sql += fmt.Sprintf(" MAXVALUE %d", maxVal.Int64)
+
+		results = append(results, SchemaObject{
+			SQL: fmt.Sprintf("CREATE TYPE %s.%s AS RANGE (\n    %s\n);",
+				QuoteIdentifier(schema),
+				QuoteIdentifier(name),
+				strings.Join(opts, ",\n    ")),
+			Drop: dropStatement("TYPE", schema, name),
+		})
+	}
+
+	return results, rows.Err()
+}
+
+// sequenceInfo holds a sequence and, for serial and identity sequences,
+// the column that owns it.
+type sequenceInfo struct {
+	schema    string
+	name      string
+	dataType  string
+	startVal  sql.NullInt64
+	incBy     sql.NullInt64
+	maxVal    sql.NullInt64
+	minVal    sql.NullInt64
+	cacheSize sql.NullInt64
+	cycle     sql.NullBool
+	lastValue sql.NullInt64
+
+	ownerSchema sql.NullString
+	ownerTable  sql.NullString
+	ownerColumn sql.NullString
+	identity    bool // owned by an identity column (created with the table)
+}
+
+// listSequences returns all user sequences. Sequences owned by an excluded
+// table are skipped along with the table.
+func listSequences(ctx context.Context, db *sql.DB, filter *dumpFilter) ([]sequenceInfo, error) {
+	query := `
+		SELECT s.schemaname, s.sequencename, s.data_type::text,
+		       s.start_value, s.increment_by, s.max_value, s.min_value, s.cache_size, s.cycle,
+		       s.last_value,
+		       tn.nspname, t.relname, a.attname, COALESCE(d.deptype = 'i', false)
+		FROM pg_sequences s
+		JOIN pg_namespace sn ON sn.nspname = s.schemaname
+		JOIN pg_class sc ON sc.relnamespace = sn.oid AND sc.relname = s.sequencename
+		LEFT JOIN pg_depend d ON d.classid = 'pg_class'::regclass
+		      AND d.objid = sc.oid
+		      AND d.refclassid = 'pg_class'::regclass
+		      AND d.deptype IN ('a', 'i')
+		LEFT JOIN pg_class t ON t.oid = d.refobjid
+		LEFT JOIN pg_namespace tn ON tn.oid = t.relnamespace
+		LEFT JOIN pg_attribute a ON a.attrelid = d.refobjid AND a.attnum = d.refobjsubid
+		WHERE s.schemaname NOT IN ('pg_catalog', 'information_schema')
+		  AND s.schemaname NOT LIKE 'pg_temp_%'
+		  AND s.schemaname NOT LIKE 'pg_toast_temp_%'
+		  AND s.sequencename NOT LIKE 'goose_%'
+		ORDER BY s.schemaname, s.sequencename
+	`
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []sequenceInfo
+	for rows.Next() {
+		var seq sequenceInfo
+		if err := rows.Scan(&seq.schema, &seq.name, &seq.dataType,
+			&seq.startVal, &seq.incBy, &seq.maxVal, &seq.minVal, &seq.cacheSize, &seq.cycle,
+			&seq.lastValue,
+			&seq.ownerSchema, &seq.ownerTable, &seq.ownerColumn, &seq.identity); err != nil {
+			return nil, fmt.Errorf("scanning sequence: %w", err)
 		}
-		if cacheSize.Valid {
-			sql += fmt.Sprintf(" CACHE %d", cacheSize.Int64)
+
+		if !filter.schema(seq.schema) {
+			continue
 		}
-		if cycle.Valid && cycle.Bool {
-			sql += " CYCLE"
+		if seq.ownerTable.Valid {
+			if !filter.table(seq.ownerSchema.String, seq.ownerTable.String) {
+				continue
+			}
 		}
-		sql += ";"
-		results = append(results, sql)
+		results = append(results, seq)
 	}
 
 	return results, rows.Err()
 }
 
+func dumpSequences(ctx context.Context, db *sql.DB, filter *dumpFilter) ([]SchemaObject, error) {
+	sequences, err := listSequences(ctx, db, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []SchemaObject
+	for _, seq := range sequences {
+		// Identity sequences are created by their column definition
+		if seq.identity {
+			continue
+		}
+
+		sql := fmt.Sprintf("CREATE SEQUENCE %s.%s",
+			QuoteIdentifier(seq.schema),
+			QuoteIdentifier(seq.name))
+
+		if seq.dataType != "" {
+			sql += " AS " + seq.dataType
+		}
+		sql += sequenceOptions(seq) + ";"
+		results = append(results, SchemaObject{SQL: sql, Drop: dropStatement("SEQUENCE", seq.schema, seq.name)})
+	}
+
+	return results, nil
+}
+
+// sequenceOptions renders the START/INCREMENT/MINVALUE/MAXVALUE/CACHE/CYCLE
+// options of a sequence, each preceded by a space.
+func sequenceOptions(seq sequenceInfo) string {
+	var opts string
+	if seq.startVal.Valid {
+		opts += fmt.Sprintf(" START WITH %d", seq.startVal.Int64)
+	}
+	if seq.incBy.Valid {
+		opts += fmt.Sprintf(" INCREMENT BY %d", seq.incBy.Int64)
+	}
+	if seq.minVal.Valid {
+		opts += fmt.Sprintf(" MINVALUE %d", seq.minVal.Int64)
+	}
+	if seq.maxVal.Valid {
+		opts += fmt.Sprintf(" MAXVALUE %d", seq.maxVal.Int64)
+	}
+	if seq.cacheSize.Valid {
+		opts += fmt.Sprintf(" CACHE %d", seq.cacheSize.Int64)
+	}
+	if seq.cycle.Valid && seq.cycle.Bool {
+		opts += " CYCLE"
+	}
+	return opts
+}
+
+// dumpSequenceOwnership dumps ALTER SEQUENCE ... OWNED BY for serial sequences
+// that belong to a table column, so dropping the table (or column) also drops the
+// sequence. Must run after tables are created.
+func dumpSequenceOwnership(ctx context.Context, db *sql.DB, filter *dumpFilter) ([]string, error) {
+	sequences, err := listSequences(ctx, db, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []string
+	for _, seq := range sequences {
+		// Identity sequences are already bound to their column
+		if seq.identity || !seq.ownerTable.Valid || !seq.ownerColumn.Valid {
+			continue
+		}
+		results = append(results, fmt.Sprintf("ALTER SEQUENCE %s.%s OWNED BY %s.%s.%s;",
+			QuoteIdentifier(seq.schema),
+			QuoteIdentifier(seq.name),
+			QuoteIdentifier(seq.ownerSchema.String),
+			QuoteIdentifier(seq.ownerTable.String),
+			QuoteIdentifier(seq.ownerColumn.String)))
+	}
+
+	return results, nil
+}
+
+// dumpSequenceValues dumps setval calls that restore each sequence's current
+// value. Sequences that have never been used (or that the current user cannot
+// read) have no last value and are left at their start value.
+func dumpSequenceValues(ctx context.Context, db *sql.DB, filter *dumpFilter) ([]string, error) {
+	sequences, err := listSequences(ctx, db, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []string
+	for _, seq := range sequences {
+		if !seq.lastValue.Valid {
+			continue
+		}
+		name := QuoteIdentifier(seq.schema) + "." + QuoteIdentifier(seq.name)
+		results = append(results, fmt.Sprintf("SELECT pg_catalog.setval(%s, %d, true);",
+			QuoteString(name), seq.lastValue.Int64))
+	}
+
+	return results, nil
+}
+
 // dumpFunctionsEarly dumps functions that use PL/pgSQL or other late-binding languages.
 // These can be created before tables since they don't validate table references at creation time.
 // This is needed for table DEFAULT expressions that reference these functions.
-func dumpFunctionsEarly(ctx context.Context, db *sql.DB) ([]string, error) {
+func dumpFunctionsEarly(ctx context.Context, db *sql.DB, filter *dumpFilter) ([]SchemaObject, error) {
 	query := `
 		SELECT n.nspname as schema,
 		       p.proname as name,
-		       pg_get_functiondef(p.oid) as definition
+		       pg_get_functiondef(p.oid) as definition,
+		       pg_get_function_identity_arguments(p.oid) as identity_args,
+		       p.prokind = 'p' as is_procedure
 		FROM pg_proc p
 		JOIN pg_namespace n ON p.pronamespace = n.oid
 		JOIN pg_language l ON p.prolang = l.oid
@@ -540,13 +998,20 @@ func dumpFunctionsEarly(ctx context.Context, db *sql.DB) ([]string, error) {
 	}
 	defer rows.Close()
 
-	var results []string
+	var results []SchemaObject
 	for rows.Next() {
-		var schema, name, definition string
-		if err := rows.Scan(&schema, &name, &definition); err != nil {
+		var schema, name, definition, identityArgs string
+		var isProcedure bool
+		if err := rows.Scan(&schema, &name, &definition, &identityArgs, &isProcedure); err != nil {
 			return nil, err
 		}
-		results = append(results, definition+";")
+		if !filter.schema(schema) {
+			continue
+		}
+		results = append(results, SchemaObject{
+			SQL:  definition + ";",
+			Drop: dropRoutineStatement(schema, name, identityArgs, isProcedure),
+		})
 	}
 
 	return results, rows.Err()
@@ -554,11 +1019,13 @@ func dumpFunctionsEarly(ctx context.Context, db *sql.DB) ([]string, error) {
 
 // dumpFunctionsLate dumps SQL language functions.
 // These must be created after tables since SQL functions validate table references at creation time.
-func dumpFunctionsLate(ctx context.Context, db *sql.DB) ([]string, error) {
+func dumpFunctionsLate(ctx context.Context, db *sql.DB, filter *dumpFilter) ([]SchemaObject, error) {
 	query := `
 		SELECT n.nspname as schema,
 		       p.proname as name,
-		       pg_get_functiondef(p.oid) as definition
+		       pg_get_functiondef(p.oid) as definition,
+		       pg_get_function_identity_arguments(p.oid) as identity_args,
+		       p.prokind = 'p' as is_procedure
 		FROM pg_proc p
 		JOIN pg_namespace n ON p.pronamespace = n.oid
 		JOIN pg_language l ON p.prolang = l.oid
@@ -581,235 +1048,1373 @@ func dumpFunctionsLate(ctx context.Context, db *sql.DB) ([]string, error) {
 	}
 	defer rows.Close()
 
-	var results []string
+	var results []SchemaObject
 	for rows.Next() {
-		var schema, name, definition string
-		if err := rows.Scan(&schema, &name, &definition); err != nil {
+		var schema, name, definition, identityArgs string
+		var isProcedure bool
+		if err := rows.Scan(&schema, &name, &definition, &identityArgs, &isProcedure); err != nil {
 			return nil, err
 		}
-		results = append(results, definition+";")
+		if !filter.schema(schema) {
+			continue
+		}
+		results = append(results, SchemaObject{
+			SQL:  definition + ";",
+			Drop: dropRoutineStatement(schema, name, identityArgs, isProcedure),
+		})
 	}
 
 	return results, rows.Err()
 }
 
-// getGeneratedColumns returns a map of column names to their generation expressions
-// for columns that are GENERATED ALWAYS AS (stored)
-func getGeneratedColumns(ctx context.Context, db *sql.DB, schema, table string) (map[string]string, error) {
-	query := `
-		SELECT a.attname, pg_get_expr(d.adbin, d.adrelid) as generation_expr
-		FROM pg_attribute a
-		JOIN pg_class c ON c.oid = a.attrelid
-		JOIN pg_namespace n ON n.oid = c.relnamespace
-		LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
-		WHERE n.nspname = $1
-		  AND c.relname = $2
-		  AND a.attnum > 0
-		  AND NOT a.attisdropped
-		  AND a.attgenerated = 's'
-	`
-	rows, err := db.QueryContext(ctx, query, schema, table)
+// operatorRefSQL renders a pg_operator OID column as OPERATOR(schema.name),
+// or NULL when unset.
+const operatorRefSQL = `CASE WHEN %[1]s <> 0 THEN (
+		SELECT 'OPERATOR(' || quote_ident(opn.nspname) || '.' || op.oprname || ')'
+		FROM pg_operator op
+		JOIN pg_namespace opn ON opn.oid = op.oprnamespace
+		WHERE op.oid = %[1]s
+	) END`
+
+func dumpOperators(ctx context.Context, db *sql.DB, filter *dumpFilter) ([]SchemaObject, error) {
+	tx, err := beginCatalogTx(ctx, db)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	result := make(map[string]string)
-	for rows.Next() {
-		var colName, expr string
-		if err := rows.Scan(&colName, &expr); err != nil {
-			return nil, err
-		}
-		result[colName] = expr
-	}
-	return result, rows.Err()
-}
-
-func dumpTables(ctx context.Context, db *sql.DB, excludeSet map[string]bool) ([]string, error) {
-	// Get all tables
-	tablesQuery := `
-		SELECT schemaname, tablename
-		FROM pg_tables
-		WHERE schemaname NOT IN ('pg_catalog', 'information_schema')
-		  AND schemaname NOT LIKE 'pg_temp_%'
-		  AND schemaname NOT LIKE 'pg_toast_temp_%'
-		  AND tablename NOT LIKE 'goose_%'
-		ORDER BY schemaname, tablename
-	`
+	defer tx.Rollback()
+
+	query := fmt.Sprintf(`
+		SELECT n.nspname, o.oprname, o.oprcode::text,
+		       CASE WHEN o.oprleft <> 0 THEN format_type(o.oprleft, NULL) END,
+		       CASE WHEN o.oprright <> 0 THEN format_type(o.oprright, NULL) END,
+		       %s,
+		       %s,
+		       CASE WHEN o.oprrest::oid <> 0 THEN o.oprrest::text END,
+		       CASE WHEN o.oprjoin::oid <> 0 THEN o.oprjoin::text END,
+		       o.oprcanhash, o.oprcanmerge
+		FROM pg_operator o
+		JOIN pg_namespace n ON n.oid = o.oprnamespace
+		WHERE n.nspname NOT IN ('pg_catalog', 'information_schema')
+		  AND n.nspname NOT LIKE 'pg_temp_%%'
+		  AND n.nspname NOT LIKE 'pg_toast_temp_%%'
+		  AND o.oprcode::oid <> 0
+		  AND NOT EXISTS (
+		      SELECT 1 FROM pg_depend d
+		      WHERE d.classid = 'pg_operator'::regclass
+		        AND d.objid = o.oid
+		        AND d.deptype = 'e'
+		  )
+		ORDER BY n.nspname, o.oprname, o.oid
+	`, fmt.Sprintf(operatorRefSQL, "o.oprcom"), fmt.Sprintf(operatorRefSQL, "o.oprnegate"))
 
-	tableRows, err := db.QueryContext(ctx, tablesQuery)
+	rows, err := tx.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
-	defer tableRows.Close()
+	defer rows.Close()
 
-	var results []string
-	for tableRows.Next() {
-		var schema, tableName string
-		if err := tableRows.Scan(&schema, &tableName); err != nil {
+	var results []SchemaObject
+	for rows.Next() {
+		var schema, name, function string
+		var left, right, commutator, negator, restrict, join sql.NullString
+		var hashes, merges bool
+		if err := rows.Scan(&schema, &name, &function, &left, &right, &commutator, &negator,
+			&restrict, &join, &hashes, &merges); err != nil {
 			return nil, err
 		}
 
-		fullName := schema + "." + tableName
-		if excludeSet[fullName] || excludeSet[tableName] {
+		if !filter.schema(schema) {
 			continue
 		}
 
-		// Get generated columns info
-		generatedCols, err := getGeneratedColumns(ctx, db, schema, tableName)
-		if err != nil {
-			return nil, fmt.Errorf("querying generated columns for %s.%s: %w", schema, tableName, err)
+		opts := []string{"FUNCTION = " + function}
+		if left.Valid {
+			opts = append(opts, "LEFTARG = "+left.String)
+		}
+		if right.Valid {
+			opts = append(opts, "RIGHTARG = "+right.String)
+		}
+		if commutator.Valid {
+			opts = append(opts, "COMMUTATOR = "+commutator.String)
+		}
+		if negator.Valid {
+			opts = append(opts, "NEGATOR = "+negator.String)
+		}
+		if restrict.Valid {
+			opts = append(opts, "RESTRICT = "+restrict.String)
+		}
+		if join.Valid {
+			opts = append(opts, "JOIN = "+join.String)
+		}
+		if hashes {
+			opts = append(opts, "HASHES")
+		}
+		if merges {
+			opts = append(opts, "MERGES")
 		}
 
-		// Get columns for this table
-		columnsQuery := `
-			SELECT column_name, data_type, character_maximum_length,
-			       is_nullable, column_default, udt_schema, udt_name,
-			       numeric_precision, numeric_scale
-			FROM information_schema.columns
-			WHERE table_schema = $1 AND table_name = $2
-			ORDER BY ordinal_position
-		`
-
-		colRows, err := db.QueryContext(ctx, columnsQuery, schema, tableName)
-		if err != nil {
-			return nil, fmt.Errorf("querying columns for %s.%s: %w", schema, tableName, err)
+		leftArg, rightArg := "NONE", "NONE"
+		if left.Valid {
+			leftArg = left.String
+		}
+		if right.Valid {
+			rightArg = right.String
 		}
 
-		var columns []string
-		for colRows.Next() {
-			var colName, dataType, udtSchema, udtName string
-			var charMaxLen, numPrecision, numScale sql.NullInt64
-			var isNullable, colDefault sql.NullString
+		// Operator names are not identifiers and must not be quoted
+		results = append(results, SchemaObject{
+			SQL: fmt.Sprintf("CREATE OPERATOR %s.%s (\n    %s\n);",
+				QuoteIdentifier(schema),
+				name,
+				strings.Join(opts, ",\n    ")),
+			Drop: fmt.Sprintf("DROP OPERATOR IF EXISTS %s.%s (%s, %s);",
+				QuoteIdentifier(schema), name, leftArg, rightArg),
+		})
+	}
 
-			if err := colRows.Scan(&colName, &dataType, &charMaxLen, &isNullable, &colDefault, &udtSchema, &udtName, &numPrecision, &numScale); err != nil {
-				colRows.Close()
-				return nil, fmt.Errorf("scanning column: %w", err)
-			}
+	return results, rows.Err()
+}
 
-			colDef := QuoteIdentifier(colName) + " "
+// dumpAggregates dumps user-defined aggregates. pg_get_functiondef cannot
+// render aggregates, so their definition is rebuilt from pg_aggregate.
+func dumpAggregates(ctx context.Context, db *sql.DB, filter *dumpFilter) ([]SchemaObject, error) {
+	tx, err := beginCatalogTx(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf(`
+		SELECT n.nspname, p.proname, pg_get_function_arguments(p.oid),
+		       pg_get_function_identity_arguments(p.oid),
+		       a.aggkind::text,
+		       a.aggtransfn::text,
+		       format_type(a.aggtranstype, NULL),
+		       a.aggtransspace,
+		       CASE WHEN a.aggfinalfn::oid <> 0 THEN a.aggfinalfn::text END,
+		       a.aggfinalextra,
+		       CASE WHEN a.aggcombinefn::oid <> 0 THEN a.aggcombinefn::text END,
+		       CASE WHEN a.aggserialfn::oid <> 0 THEN a.aggserialfn::text END,
+		       CASE WHEN a.aggdeserialfn::oid <> 0 THEN a.aggdeserialfn::text END,
+		       CASE WHEN a.aggmtransfn::oid <> 0 THEN a.aggmtransfn::text END,
+		       CASE WHEN a.aggminvtransfn::oid <> 0 THEN a.aggminvtransfn::text END,
+		       CASE WHEN a.aggmtranstype <> 0 THEN format_type(a.aggmtranstype, NULL) END,
+		       a.aggmtransspace,
+		       CASE WHEN a.aggmfinalfn::oid <> 0 THEN a.aggmfinalfn::text END,
+		       a.aggmfinalextra,
+		       a.agginitval,
+		       a.aggminitval,
+		       %s,
+		       p.proparallel::text
+		FROM pg_aggregate a
+		JOIN pg_proc p ON p.oid = a.aggfnoid
+		JOIN pg_namespace n ON n.oid = p.pronamespace
+		WHERE n.nspname NOT IN ('pg_catalog', 'information_schema')
+		  AND n.nspname NOT LIKE 'pg_temp_%%'
+		  AND n.nspname NOT LIKE 'pg_toast_temp_%%'
+		  AND NOT EXISTS (
+		      SELECT 1 FROM pg_depend d
+		      WHERE d.classid = 'pg_proc'::regclass
+		        AND d.objid = p.oid
+		        AND d.deptype = 'e'
+		  )
+		ORDER BY n.nspname, p.proname, p.oid
+	`, fmt.Sprintf(operatorRefSQL, "a.aggsortop"))
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []SchemaObject
+	for rows.Next() {
+		var schema, name, args, identityArgs, kind, sfunc, stype, parallel string
+		var sspace, msspace int64
+		var finalExtra, mfinalExtra bool
+		var finalfn, combinefn, serialfn, deserialfn, msfunc, minvfunc, mstype, mfinalfn sql.NullString
+		var initcond, minitcond, sortop sql.NullString
+		if err := rows.Scan(&schema, &name, &args, &identityArgs, &kind, &sfunc, &stype, &sspace,
+			&finalfn, &finalExtra, &combinefn, &serialfn, &deserialfn,
+			&msfunc, &minvfunc, &mstype, &msspace, &mfinalfn, &mfinalExtra,
+			&initcond, &minitcond, &sortop, &parallel); err != nil {
+			return nil, err
+		}
+
+		if !filter.schema(schema) {
+			continue
+		}
+
+		if args == "" {
+			args = "*"
+		}
+		if identityArgs == "" {
+			identityArgs = "*"
+		}
+
+		opts := []string{"SFUNC = " + sfunc, "STYPE = " + stype}
+		if sspace != 0 {
+			opts = append(opts, fmt.Sprintf("SSPACE = %d", sspace))
+		}
+		if finalfn.Valid {
+			opts = append(opts, "FINALFUNC = "+finalfn.String)
+			if finalExtra {
+				opts = append(opts, "FINALFUNC_EXTRA")
+			}
+		}
+		if combinefn.Valid {
+			opts = append(opts, "COMBINEFUNC = "+combinefn.String)
+		}
+		if serialfn.Valid {
+			opts = append(opts, "SERIALFUNC = "+serialfn.String)
+		}
+		if deserialfn.Valid {
+			opts = append(opts, "DESERIALFUNC = "+deserialfn.String)
+		}
+		if initcond.Valid {
+			opts = append(opts, "INITCOND = "+QuoteString(initcond.String))
+		}
+		if msfunc.Valid {
+			opts = append(opts, "MSFUNC = "+msfunc.String)
+		}
+		if minvfunc.Valid {
+			opts = append(opts, "MINVFUNC = "+minvfunc.String)
+		}
+		if mstype.Valid {
+			opts = append(opts, "MSTYPE = "+mstype.String)
+		}
+		if msspace != 0 {
+			opts = append(opts, fmt.Sprintf("MSSPACE = %d", msspace))
+		}
+		if mfinalfn.Valid {
+			opts = append(opts, "MFINALFUNC = "+mfinalfn.String)
+			if mfinalExtra {
+				opts = append(opts, "MFINALFUNC_EXTRA")
+			}
+		}
+		if minitcond.Valid {
+			opts = append(opts, "MINITCOND = "+QuoteString(minitcond.String))
+		}
+		if sortop.Valid {
+			opts = append(opts, "SORTOP = "+sortop.String)
+		}
+		switch parallel {
+		case "s":
+			opts = append(opts, "PARALLEL = SAFE")
+		case "r":
+			opts = append(opts, "PARALLEL = RESTRICTED")
+		}
+		if kind == "h" {
+			opts = append(opts, "HYPOTHETICAL")
+		}
+
+		results = append(results, SchemaObject{
+			SQL: fmt.Sprintf("CREATE AGGREGATE %s.%s(%s) (\n    %s\n);",
+				QuoteIdentifier(schema),
+				QuoteIdentifier(name),
+				args,
+				strings.Join(opts, ",\n    ")),
+			Drop: fmt.Sprintf("DROP AGGREGATE IF EXISTS %s.%s(%s);",
+				QuoteIdentifier(schema), QuoteIdentifier(name), identityArgs),
+		})
+	}
 
-			// Determine the type string
-			switch dataType {
-			case "character varying":
-				if charMaxLen.Valid {
-					colDef += fmt.Sprintf("varchar(%d)", charMaxLen.Int64)
-				} else {
-					colDef += "varchar"
+	return results, rows.Err()
+}
+
+// dumpCasts dumps user-defined casts. A cast is included only if every type
+// and function it uses outside pg_catalog is in a dumped schema.
+func dumpCasts(ctx context.Context, db *sql.DB, filter *dumpFilter) ([]SchemaObject, error) {
+	tx, err := beginCatalogTx(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// OIDs below 16384 (FirstNormalObjectId) are built-in casts
+	query := `
+		SELECT format_type(c.castsource, NULL), format_type(c.casttarget, NULL),
+		       c.castmethod::text, c.castcontext::text,
+		       CASE WHEN c.castfunc <> 0 THEN c.castfunc::regprocedure::text END,
+		       sn.nspname, tn.nspname, fn.nspname
+		FROM pg_cast c
+		JOIN pg_type st ON st.oid = c.castsource
+		JOIN pg_namespace sn ON sn.oid = st.typnamespace
+		JOIN pg_type tt ON tt.oid = c.casttarget
+		JOIN pg_namespace tn ON tn.oid = tt.typnamespace
+		LEFT JOIN pg_proc f ON f.oid = c.castfunc
+		LEFT JOIN pg_namespace fn ON fn.oid = f.pronamespace
+		WHERE c.oid >= 16384
+		  AND NOT EXISTS (
+		      SELECT 1 FROM pg_depend d
+		      WHERE d.classid = 'pg_cast'::regclass
+		        AND d.objid = c.oid
+		        AND d.deptype = 'e'
+		  )
+		ORDER BY 1, 2
+	`
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []SchemaObject
+	for rows.Next() {
+		var source, target, method, castContext, sourceSchema, targetSchema string
+		var function, funcSchema sql.NullString
+		if err := rows.Scan(&source, &target, &method, &castContext, &function,
+			&sourceSchema, &targetSchema, &funcSchema); err != nil {
+			return nil, err
+		}
+
+		included := true
+		for _, schema := range []string{sourceSchema, targetSchema, funcSchema.String} {
+			if schema != "" && schema != "pg_catalog" && !filter.schema(schema) {
+				included = false
+			}
+		}
+		if !included {
+			continue
+		}
+
+		stmt := fmt.Sprintf("CREATE CAST (%s AS %s)", source, target)
+		switch method {
+		case "f":
+			stmt += " WITH FUNCTION " + function.String
+		case "i":
+			stmt += " WITH INOUT"
+		default:
+			stmt += " WITHOUT FUNCTION"
+		}
+		switch castContext {
+		case "a":
+			stmt += " AS ASSIGNMENT"
+		case "i":
+			stmt += " AS IMPLICIT"
+		}
+		results = append(results, SchemaObject{
+			SQL:  stmt + ";",
+			Drop: fmt.Sprintf("DROP CAST IF EXISTS (%s AS %s);", source, target),
+		})
+	}
+
+	return results, rows.Err()
+}
+
+// tableRef identifies a table by OID and name.
+type tableRef struct {
+	oid    int64
+	schema string
+	name   string
+}
+
+// tableInfo holds a table and its table-level storage attributes.
+type tableInfo struct {
+	tableRef
+	unlogged   bool
+	reloptions []string
+	tablespace sql.NullString
+	parents    []string // quoted and schema-qualified, in INHERITS order
+
+	// partitionOf is the parent of a partition, quoted and schema-qualified,
+	// and partitionBound its FOR VALUES clause
+	partitionOf    sql.NullString
+	partitionBound sql.NullString
+	// partitionKey is the PARTITION BY clause of a partitioned table
+	partitionKey sql.NullString
+}
+
+// storageNames maps pg_attribute.attstorage to its SET STORAGE keyword.
+var storageNames = map[string]string{
+	"p": "PLAIN",
+	"e": "EXTERNAL",
+	"m": "MAIN",
+	"x": "EXTENDED",
+}
+
+// compressionNames maps pg_attribute.attcompression to its COMPRESSION keyword.
+var compressionNames = map[string]string{
+	"p": "pglz",
+	"l": "lz4",
+}
+
+// dumpTables dumps CREATE TABLE statements, with parent tables ahead of the
+// tables that inherit from them. Partitions are created with PARTITION OF,
+// which gives them the parent's columns, and later the constraints, indexes
+// and triggers added to the parent.
+func dumpTables(ctx context.Context, db *sql.DB, filter *dumpFilter, tablespaces bool) ([]SchemaObject, error) {
+	version, err := serverVersionNum(ctx, db)
+	if err != nil {
+		return nil, fmt.Errorf("reading server version: %w", err)
+	}
+
+	// Identity columns carry their sequence options inline
+	sequences, err := listSequences(ctx, db, filter)
+	if err != nil {
+		return nil, fmt.Errorf("listing sequences: %w", err)
+	}
+	identitySeqs := make(map[string]sequenceInfo)
+	for _, seq := range sequences {
+		if seq.identity {
+			key := seq.ownerSchema.String + "." + seq.ownerTable.String + "." + seq.ownerColumn.String
+			identitySeqs[key] = seq
+		}
+	}
+
+	tx, err := beginCatalogTx(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Get all tables. Partitions are not dumped as inheritance children.
+	tablesQuery := `
+		SELECT c.oid, n.nspname, c.relname,
+		       c.relpersistence = 'u',
+		       c.reloptions,
+		       ts.spcname,
+		       ARRAY(
+		           SELECT '"' || replace(pn.nspname, '"', '""') || '"."' ||
+		                  replace(pc.relname, '"', '""') || '"'
+		           FROM pg_inherits i
+		           JOIN pg_class pc ON pc.oid = i.inhparent
+		           JOIN pg_namespace pn ON pn.oid = pc.relnamespace
+		           WHERE i.inhrelid = c.oid AND NOT c.relispartition
+		           ORDER BY i.inhseqno
+		       ),
+		       (
+		           SELECT '"' || replace(pn.nspname, '"', '""') || '"."' ||
+		                  replace(pc.relname, '"', '""') || '"'
+		           FROM pg_inherits i
+		           JOIN pg_class pc ON pc.oid = i.inhparent
+		           JOIN pg_namespace pn ON pn.oid = pc.relnamespace
+		           WHERE i.inhrelid = c.oid AND c.relispartition
+		       ),
+		       CASE WHEN c.relispartition THEN pg_get_expr(c.relpartbound, c.oid) END,
+		       CASE WHEN c.relkind = 'p' THEN pg_get_partkeydef(c.oid) END
+		FROM pg_class c
+		JOIN pg_namespace n ON c.relnamespace = n.oid
+		LEFT JOIN pg_tablespace ts ON ts.oid = c.reltablespace
+		WHERE c.relkind IN ('r', 'p')
+		  AND n.nspname NOT IN ('pg_catalog', 'information_schema')
+		  AND n.nspname NOT LIKE 'pg_temp_%'
+		  AND n.nspname NOT LIKE 'pg_toast_temp_%'
+		  AND c.relname NOT LIKE 'goose_%'
+		ORDER BY n.nspname, c.relname
+	`
+
+	tableRows, err := tx.QueryContext(ctx, tablesQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	var tables []tableInfo
+	for tableRows.Next() {
+		var t tableInfo
+		if err := tableRows.Scan(&t.oid, &t.schema, &t.name, &t.unlogged,
+			pq.Array(&t.reloptions), &t.tablespace, pq.Array(&t.parents),
+			&t.partitionOf, &t.partitionBound, &t.partitionKey); err != nil {
+			tableRows.Close()
+			return nil, err
+		}
+
+		if !filter.table(t.schema, t.name) {
+			continue
+		}
+		tables = append(tables, t)
+	}
+	tableRows.Close()
+	if err := tableRows.Err(); err != nil {
+		return nil, err
+	}
+
+	// A partition whose parent is left out of the dump becomes a standalone
+	// table with all of its columns
+	dumped := make(map[string]bool, len(tables))
+	for _, t := range tables {
+		dumped[QuoteIdentifier(t.schema)+"."+QuoteIdentifier(t.name)] = true
+	}
+	for i, t := range tables {
+		if t.partitionOf.Valid && !dumped[t.partitionOf.String] {
+			tables[i].partitionOf = sql.NullString{}
+		}
+	}
+
+	tables = orderTablesByInheritance(tables)
+
+	// attcompression was added in PostgreSQL 14
+	compressionExpr := "''"
+	if version >= 140000 {
+		compressionExpr = "a.attcompression::text"
+	}
+
+	// Get columns for a table
+	columnsQuery := fmt.Sprintf(`
+		SELECT a.attname,
+		       format_type(a.atttypid, a.atttypmod),
+		       a.attnotnull,
+		       pg_get_expr(d.adbin, d.adrelid),
+		       a.attidentity::text,
+		       a.attgenerated::text,
+		       CASE WHEN a.attcollation <> 0 AND a.attcollation <> t.typcollation
+		            THEN quote_ident(cn.nspname) || '.' || quote_ident(co.collname)
+		       END,
+		       a.attstorage::text,
+		       t.typstorage::text,
+		       %s,
+		       a.attislocal
+		FROM pg_attribute a
+		JOIN pg_type t ON t.oid = a.atttypid
+		LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+		LEFT JOIN pg_collation co ON co.oid = a.attcollation
+		LEFT JOIN pg_namespace cn ON cn.oid = co.collnamespace
+		WHERE a.attrelid = $1
+		  AND a.attnum > 0
+		  AND NOT a.attisdropped
+		ORDER BY a.attnum
+	`, compressionExpr)
+
+	var results []SchemaObject
+	for _, t := range tables {
+		if t.partitionOf.Valid {
+			sql := fmt.Sprintf("CREATE TABLE %s.%s PARTITION OF %s %s",
+				QuoteIdentifier(t.schema),
+				QuoteIdentifier(t.name),
+				t.partitionOf.String,
+				t.partitionBound.String)
+			if t.partitionKey.Valid {
+				sql += " PARTITION BY " + t.partitionKey.String
+			}
+			if len(t.reloptions) > 0 {
+				sql += " WITH (" + strings.Join(t.reloptions, ", ") + ")"
+			}
+			if tablespaces && t.tablespace.Valid {
+				sql += " TABLESPACE " + QuoteIdentifier(t.tablespace.String)
+			}
+			results = append(results, SchemaObject{SQL: sql + ";", Drop: dropStatement("TABLE", t.schema, t.name)})
+			continue
+		}
+
+		colRows, err := tx.QueryContext(ctx, columnsQuery, t.oid)
+		if err != nil {
+			return nil, fmt.Errorf("querying columns for %s.%s: %w", t.schema, t.name, err)
+		}
+
+		var columns, storage []string
+		for colRows.Next() {
+			var colName, colType, identity, generated, attStorage, typStorage, compression string
+			var notNull, local bool
+			var expr, collation sql.NullString
+
+			if err := colRows.Scan(&colName, &colType, &notNull, &expr, &identity, &generated,
+				&collation, &attStorage, &typStorage, &compression, &local); err != nil {
+				colRows.Close()
+				return nil, fmt.Errorf("scanning column: %w", err)
+			}
+			// INHERITS recreates the columns of the parents
+			if !local && len(t.parents) > 0 {
+				continue
+			}
+
+			colDef := QuoteIdentifier(colName) + " " + colType
+
+			if name, ok := compressionNames[compression]; ok {
+				colDef += " COMPRESSION " + name
+			}
+			if collation.Valid {
+				colDef += " COLLATE " + collation.String
+			}
+
+			switch {
+			case generated == "s" || generated == "v":
+				// Generated columns cannot have DEFAULT
+				kind := "STORED"
+				if generated == "v" {
+					kind = "VIRTUAL"
 				}
-			case "character":
-				if charMaxLen.Valid {
-					colDef += fmt.Sprintf("char(%d)", charMaxLen.Int64)
-				} else {
-					colDef += "char"
+				colDef += " GENERATED ALWAYS AS (" + expr.String + ") " + kind
+				if notNull {
+					colDef += " NOT NULL"
 				}
-			case "numeric":
-				if numPrecision.Valid && numScale.Valid {
-					colDef += fmt.Sprintf("numeric(%d,%d)", numPrecision.Int64, numScale.Int64)
-				} else if numPrecision.Valid {
-					colDef += fmt.Sprintf("numeric(%d)", numPrecision.Int64)
-				} else {
-					colDef += "numeric"
+			case identity != "":
+				mode := "ALWAYS"
+				if identity == "d" {
+					mode = "BY DEFAULT"
 				}
-			case "ARRAY":
-				// Use udt_name which includes the array indicator (e.g., _text for text[])
-				colDef += udtName
-			case "USER-DEFINED":
-				// Custom type like enum or composite - use schema-qualified name
-				if udtSchema != "public" {
-					colDef += udtSchema + "." + udtName
-				} else {
-					colDef += udtName
+				colDef += " GENERATED " + mode + " AS IDENTITY"
+				if seq, ok := identitySeqs[t.schema+"."+t.name+"."+colName]; ok {
+					colDef += fmt.Sprintf(" (SEQUENCE NAME %s.%s%s)",
+						QuoteIdentifier(seq.schema),
+						QuoteIdentifier(seq.name),
+						sequenceOptions(seq))
 				}
+				colDef += " NOT NULL"
 			default:
-				colDef += dataType
-			}
-
-			// Check if this is a generated column
-			if genExpr, isGenerated := generatedCols[colName]; isGenerated {
-				colDef += " GENERATED ALWAYS AS (" + genExpr + ") STORED"
-				if isNullable.Valid && isNullable.String == "NO" {
+				if notNull {
 					colDef += " NOT NULL"
 				}
-				// Generated columns cannot have DEFAULT, skip it
-			} else {
-				// Add NOT NULL if applicable
-				if isNullable.Valid && isNullable.String == "NO" {
-					colDef += " NOT NULL"
+				if expr.Valid && expr.String != "" {
+					colDef += " DEFAULT " + expr.String
 				}
-				// Add DEFAULT if applicable
-				if colDefault.Valid && colDefault.String != "" {
-					colDef += " DEFAULT " + colDefault.String
+			}
+
+			if attStorage != typStorage {
+				if name, ok := storageNames[attStorage]; ok {
+					storage = append(storage, fmt.Sprintf("ALTER TABLE ONLY %s.%s ALTER COLUMN %s SET STORAGE %s;",
+						QuoteIdentifier(t.schema),
+						QuoteIdentifier(t.name),
+						QuoteIdentifier(colName),
+						name))
 				}
 			}
 
-			columns = append(columns, colDef)
+			columns = append(columns, colDef)
+		}
+		colRows.Close()
+		if err := colRows.Err(); err != nil {
+			return nil, fmt.Errorf("iterating columns for %s.%s: %w", t.schema, t.name, err)
+		}
+
+		if len(columns) == 0 && len(t.parents) == 0 {
+			continue
+		}
+
+		create := "CREATE TABLE"
+		if t.unlogged {
+			create = "CREATE UNLOGGED TABLE"
+		}
+		sql := fmt.Sprintf("%s %s.%s (\n    %s\n)",
+			create,
+			QuoteIdentifier(t.schema),
+			QuoteIdentifier(t.name),
+			strings.Join(columns, ",\n    "))
+		if len(columns) == 0 {
+			sql = fmt.Sprintf("%s %s.%s ()", create, QuoteIdentifier(t.schema), QuoteIdentifier(t.name))
+		}
+		if len(t.parents) > 0 {
+			sql += " INHERITS (" + strings.Join(t.parents, ", ") + ")"
+		}
+		if t.partitionKey.Valid {
+			sql += " PARTITION BY " + t.partitionKey.String
+		}
+		if len(t.reloptions) > 0 {
+			sql += " WITH (" + strings.Join(t.reloptions, ", ") + ")"
+		}
+		if tablespaces && t.tablespace.Valid {
+			sql += " TABLESPACE " + QuoteIdentifier(t.tablespace.String)
+		}
+		results = append(results, SchemaObject{SQL: sql + ";", Drop: dropStatement("TABLE", t.schema, t.name)})
+		results = append(results, statements(storage)...)
+	}
+
+	return results, nil
+}
+
+// orderTablesByInheritance moves every parent table ahead of the tables that
+// inherit from it or are its partitions, keeping the name order otherwise. Parents that are not
+// being dumped are ignored.
+func orderTablesByInheritance(tables []tableInfo) []tableInfo {
+	byName := make(map[string]tableInfo, len(tables))
+	for _, t := range tables {
+		byName[QuoteIdentifier(t.schema)+"."+QuoteIdentifier(t.name)] = t
+	}
+
+	ordered := make([]tableInfo, 0, len(tables))
+	visited := make(map[string]bool, len(tables))
+	var visit func(t tableInfo)
+	visit = func(t tableInfo) {
+		key := QuoteIdentifier(t.schema) + "." + QuoteIdentifier(t.name)
+		if visited[key] {
+			return
+		}
+		visited[key] = true
+		for _, parent := range t.parents {
+			if p, ok := byName[parent]; ok {
+				visit(p)
+			}
+		}
+		if p, ok := byName[t.partitionOf.String]; ok && t.partitionOf.Valid {
+			visit(p)
+		}
+		ordered = append(ordered, t)
+	}
+	for _, t := range tables {
+		visit(t)
+	}
+	return ordered
+}
+
+// beginCatalogTx starts a read-only transaction whose search_path contains
+// only pg_catalog, so format_type, pg_get_expr and reg* casts schema-qualify
+// every type, function and operator outside pg_catalog (including public).
+// The caller must roll the transaction back.
+func beginCatalogTx(ctx context.Context, db *sql.DB) (*sql.Tx, error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("starting transaction: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "SET LOCAL search_path = pg_catalog"); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("setting search_path: %w", err)
+	}
+	return tx, nil
+}
+
+// serverVersionNum returns the server version as a number (e.g. 160002).
+func serverVersionNum(ctx context.Context, db *sql.DB) (int, error) {
+	var version int
+	err := db.QueryRowContext(ctx, "SELECT current_setting('server_version_num')::int").Scan(&version)
+	return version, err
+}
+
+// viewInfo holds a view or materialized view read from pg_class.
+type viewInfo struct {
+	oid          int64
+	schema       string
+	name         string
+	materialized bool
+	populated    bool
+	definition   string
+}
+
+// dumpViews dumps views and materialized views in dependency order.
+// Materialized views are followed by their indexes, so unique indexes needed
+// for REFRESH MATERIALIZED VIEW CONCURRENTLY are created alongside them.
+func dumpViews(ctx context.Context, db *sql.DB, filter *dumpFilter, matviewsNoData bool) ([]SchemaObject, error) {
+	query := `
+		SELECT c.oid, n.nspname, c.relname, c.relkind = 'm', c.relispopulated,
+		       pg_get_viewdef(c.oid)
+		FROM pg_class c
+		JOIN pg_namespace n ON c.relnamespace = n.oid
+		WHERE c.relkind IN ('v', 'm')
+		  AND n.nspname NOT IN ('pg_catalog', 'information_schema')
+		  AND n.nspname NOT LIKE 'pg_temp_%'
+		  AND n.nspname NOT LIKE 'pg_toast_temp_%'
+		  AND NOT EXISTS (
+		      SELECT 1 FROM pg_depend d
+		      WHERE d.objid = c.oid
+		        AND d.deptype = 'e'
+		  )
+		ORDER BY n.nspname, c.relname
+	`
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var views []viewInfo
+	for rows.Next() {
+		var v viewInfo
+		if err := rows.Scan(&v.oid, &v.schema, &v.name, &v.materialized, &v.populated, &v.definition); err != nil {
+			return nil, err
+		}
+
+		if !filter.table(v.schema, v.name) {
+			continue
+		}
+		views = append(views, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	ordered, err := orderViews(ctx, db, views)
+	if err != nil {
+		return nil, fmt.Errorf("ordering views: %w", err)
+	}
+
+	var results []SchemaObject
+	for _, v := range ordered {
+		definition := strings.TrimSuffix(strings.TrimSpace(v.definition), ";")
+
+		if !v.materialized {
+			results = append(results, SchemaObject{
+				SQL: fmt.Sprintf("CREATE VIEW %s.%s AS\n%s;",
+					QuoteIdentifier(v.schema),
+					QuoteIdentifier(v.name),
+					definition),
+				Drop: dropStatement("VIEW", v.schema, v.name),
+			})
+			continue
+		}
+
+		withData := "WITH DATA"
+		if matviewsNoData || !v.populated {
+			withData = "WITH NO DATA"
+		}
+		results = append(results, SchemaObject{
+			SQL: fmt.Sprintf("CREATE MATERIALIZED VIEW %s.%s AS\n%s\n%s;",
+				QuoteIdentifier(v.schema),
+				QuoteIdentifier(v.name),
+				definition,
+				withData),
+			Drop: dropStatement("MATERIALIZED VIEW", v.schema, v.name),
+		})
+
+		indexes, err := dumpRelationIndexes(ctx, db, v.schema, v.name)
+		if err != nil {
+			return nil, fmt.Errorf("dumping indexes for materialized view %s.%s: %w", v.schema, v.name, err)
+		}
+		// Indexes go away with their materialized view
+		results = append(results, statements(indexes)...)
+	}
+
+	return results, nil
+}
+
+// orderViews sorts views so that every view comes after the views and
+// materialized views it selects from. Views without dependencies between
+// them keep their name order.
+func orderViews(ctx context.Context, db *sql.DB, views []viewInfo) ([]viewInfo, error) {
+	if len(views) == 0 {
+		return nil, nil
+	}
+
+	// A view depends on another relation through its rewrite rule
+	query := `
+		SELECT DISTINCT r.ev_class, d.refobjid
+		FROM pg_rewrite r
+		JOIN pg_depend d ON d.classid = 'pg_rewrite'::regclass AND d.objid = r.oid
+		JOIN pg_class c ON c.oid = d.refobjid
+		WHERE d.refclassid = 'pg_class'::regclass
+		  AND d.refobjid <> r.ev_class
+		  AND c.relkind IN ('v', 'm')
+	`
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byOID := make(map[int64]bool, len(views))
+	for _, v := range views {
+		byOID[v.oid] = true
+	}
+
+	deps := make(map[int64][]int64) // view -> views it depends on
+	for rows.Next() {
+		var dependent, referenced int64
+		if err := rows.Scan(&dependent, &referenced); err != nil {
+			return nil, err
+		}
+		if byOID[dependent] && byOID[referenced] {
+			deps[dependent] = append(deps[dependent], referenced)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Depth-first topological sort, visiting views in name order
+	var result []viewInfo
+	index := make(map[int64]viewInfo, len(views))
+	for _, v := range views {
+		index[v.oid] = v
+	}
+	visited := make(map[int64]bool, len(views))
+	var visit func(oid int64)
+	visit = func(oid int64) {
+		if visited[oid] {
+			return
+		}
+		visited[oid] = true
+		for _, dep := range deps[oid] {
+			visit(dep)
+		}
+		result = append(result, index[oid])
+	}
+	for _, v := range views {
+		visit(v.oid)
+	}
+
+	return result, nil
+}
+
+// dumpRelationIndexes returns the CREATE INDEX statements for a single relation.
+func dumpRelationIndexes(ctx context.Context, db *sql.DB, schema, name string) ([]string, error) {
+	query := `
+		SELECT indexdef
+		FROM pg_indexes
+		WHERE schemaname = $1 AND tablename = $2
+		ORDER BY indexname
+	`
+
+	rows, err := db.QueryContext(ctx, query, schema, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []string
+	for rows.Next() {
+		var indexDef string
+		if err := rows.Scan(&indexDef); err != nil {
+			return nil, err
+		}
+		results = append(results, indexDef+";")
+	}
+
+	return results, rows.Err()
+}
+
+func dumpPrimaryKeys(ctx context.Context, db *sql.DB, filter *dumpFilter) ([]SchemaObject, error) {
+	return dumpConstraints(ctx, db, filter, "p")
+}
+
+func dumpUniqueConstraints(ctx context.Context, db *sql.DB, filter *dumpFilter) ([]SchemaObject, error) {
+	return dumpConstraints(ctx, db, filter, "u")
+}
+
+func dumpCheckConstraints(ctx context.Context, db *sql.DB, filter *dumpFilter) ([]SchemaObject, error) {
+	return dumpConstraints(ctx, db, filter, "c")
+}
+
+// dumpExclusionConstraints dumps EXCLUDE constraints (e.g. EXCLUDE USING gist).
+// Their backing indexes are skipped by dumpIndexes, so this is the only place
+// they are recreated.
+func dumpExclusionConstraints(ctx context.Context, db *sql.DB, filter *dumpFilter) ([]SchemaObject, error) {
+	return dumpConstraints(ctx, db, filter, "x")
+}
+
+func dumpForeignKeys(ctx context.Context, db *sql.DB, filter *dumpFilter) ([]SchemaObject, error) {
+	return dumpConstraints(ctx, db, filter, "f")
+}
+
+// dumpConstraints dumps table constraints of the given pg_constraint.contype.
+// pg_get_constraintdef renders the complete definition, including
+// DEFERRABLE / INITIALLY DEFERRED, NOT VALID, NO INHERIT and
+// NULLS NOT DISTINCT, so those attributes survive the round trip.
+// Constraints inherited from a parent table are recreated by the parent's.
+func dumpConstraints(ctx context.Context, db *sql.DB, filter *dumpFilter, contype string) ([]SchemaObject, error) {
+	version, err := serverVersionNum(ctx, db)
+	if err != nil {
+		return nil, fmt.Errorf("reading server version: %w", err)
+	}
+	// conparentid (PostgreSQL 11) marks constraints cloned from a
+	// partitioned table's
+	clonedExpr := "false"
+	if version >= 110000 {
+		clonedExpr = "con.conparentid <> 0"
+	}
+
+	query := fmt.Sprintf(`
+		SELECT n.nspname as schema, c.relname as table_name,
+		       con.conname as constraint_name,
+		       pg_get_constraintdef(con.oid) as constraint_def,
+		       rn.nspname as ref_schema, rc.relname as ref_table,
+		       NOT con.conislocal OR %s as inherited,
+		       pn.nspname as partition_schema, pc.relname as partition_parent
+		FROM pg_constraint con
+		JOIN pg_class c ON con.conrelid = c.oid
+		JOIN pg_namespace n ON c.relnamespace = n.oid
+		LEFT JOIN pg_class rc ON con.confrelid = rc.oid
+		LEFT JOIN pg_namespace rn ON rc.relnamespace = rn.oid
+		LEFT JOIN pg_inherits i ON i.inhrelid = c.oid AND c.relispartition
+		LEFT JOIN pg_class pc ON pc.oid = i.inhparent
+		LEFT JOIN pg_namespace pn ON pn.oid = pc.relnamespace
+		WHERE con.contype = $1
+		  AND n.nspname NOT IN ('pg_catalog', 'information_schema')
+		  AND n.nspname NOT LIKE 'pg_temp_%%'
+		  AND n.nspname NOT LIKE 'pg_toast_temp_%%'
+		ORDER BY n.nspname, c.relname, con.conname
+	`, clonedExpr)
+
+	rows, err := db.QueryContext(ctx, query, contype)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []SchemaObject
+	for rows.Next() {
+		var schema, tableName, conName, conDef string
+		var refSchema, refTable, partitionSchema, partitionParent sql.NullString
+		var inherited bool
+		if err := rows.Scan(&schema, &tableName, &conName, &conDef, &refSchema, &refTable,
+			&inherited, &partitionSchema, &partitionParent); err != nil {
+			return nil, err
+		}
+
+		if !filter.table(schema, tableName) {
+			continue
+		}
+		// INHERITS and PARTITION OF recreate inherited constraints, except
+		// on partitions dumped as standalone tables
+		if inherited && !(partitionParent.Valid && !filter.table(partitionSchema.String, partitionParent.String)) {
+			continue
+		}
+		// Skip foreign keys that reference a table left out of the dump
+		if refTable.Valid && !filter.table(refSchema.String, refTable.String) {
+			continue
+		}
+
+		sql := fmt.Sprintf("ALTER TABLE %s.%s ADD CONSTRAINT %s %s;",
+			QuoteIdentifier(schema),
+			QuoteIdentifier(tableName),
+			QuoteIdentifier(conName),
+			conDef)
+		results = append(results, SchemaObject{
+			SQL: sql,
+			Drop: fmt.Sprintf("ALTER TABLE IF EXISTS %s.%s DROP CONSTRAINT IF EXISTS %s;",
+				QuoteIdentifier(schema),
+				QuoteIdentifier(tableName),
+				QuoteIdentifier(conName)),
+		})
+	}
+
+	return results, rows.Err()
+}
+
+func dumpIndexes(ctx context.Context, db *sql.DB, filter *dumpFilter) ([]SchemaObject, error) {
+	// Get indexes that are not backing constraints (those are created by
+	// the constraint itself). Materialized view indexes are dumped together
+	// with their views.
+	query := `
+		SELECT i.schemaname, i.tablename, i.indexname, i.indexdef,
+		       ic.relispartition, pn.nspname, pc.relname
+		FROM pg_indexes i
+		JOIN pg_namespace n ON n.nspname = i.schemaname
+		JOIN pg_class c ON c.relnamespace = n.oid AND c.relname = i.tablename
+		JOIN pg_class ic ON ic.relnamespace = n.oid AND ic.relname = i.indexname
+		LEFT JOIN pg_inherits inh ON inh.inhrelid = c.oid AND c.relispartition
+		LEFT JOIN pg_class pc ON pc.oid = inh.inhparent
+		LEFT JOIN pg_namespace pn ON pn.oid = pc.relnamespace
+		WHERE i.schemaname NOT IN ('pg_catalog', 'information_schema')
+		  AND i.schemaname NOT LIKE 'pg_temp_%'
+		  AND i.schemaname NOT LIKE 'pg_toast_temp_%'
+		  AND c.relkind <> 'm'
+		  AND NOT EXISTS (
+		      SELECT 1 FROM pg_constraint con
+		      WHERE con.conindid = ic.oid
+		        AND con.conrelid = c.oid
+		        AND con.contype IN ('p', 'u', 'x')
+		  )
+		ORDER BY i.schemaname, i.tablename, i.indexname
+	`
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []SchemaObject
+	for rows.Next() {
+		var schema, tableName, indexName, indexDef string
+		var attached bool
+		var partitionSchema, partitionParent sql.NullString
+		if err := rows.Scan(&schema, &tableName, &indexName, &indexDef,
+			&attached, &partitionSchema, &partitionParent); err != nil {
+			return nil, err
+		}
+
+		if !filter.table(schema, tableName) {
+			continue
+		}
+		// Indexes attached to a partitioned table's index are created with
+		// it, except on partitions dumped as standalone tables
+		if attached && partitionParent.Valid && filter.table(partitionSchema.String, partitionParent.String) {
+			continue
+		}
+
+		results = append(results, SchemaObject{SQL: indexDef + ";", Drop: dropStatement("INDEX", schema, indexName)})
+	}
+
+	return results, rows.Err()
+}
+
+// dumpReplicaIdentity dumps REPLICA IDENTITY for tables that don't use the
+// default (their primary key).
+func dumpReplicaIdentity(ctx context.Context, db *sql.DB, filter *dumpFilter) ([]string, error) {
+	query := `
+		SELECT n.nspname, c.relname, c.relreplident::text, ic.relname
+		FROM pg_class c
+		JOIN pg_namespace n ON c.relnamespace = n.oid
+		LEFT JOIN pg_index i ON i.indrelid = c.oid AND i.indisreplident
+		LEFT JOIN pg_class ic ON ic.oid = i.indexrelid
+		WHERE c.relkind IN ('r', 'p')
+		  AND c.relreplident <> 'd'
+		  AND n.nspname NOT IN ('pg_catalog', 'information_schema')
+		  AND n.nspname NOT LIKE 'pg_temp_%'
+		  AND n.nspname NOT LIKE 'pg_toast_temp_%'
+		  AND c.relname NOT LIKE 'goose_%'
+		ORDER BY n.nspname, c.relname
+	`
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []string
+	for rows.Next() {
+		var schema, tableName, identity string
+		var index sql.NullString
+		if err := rows.Scan(&schema, &tableName, &identity, &index); err != nil {
+			return nil, err
+		}
+
+		if !filter.table(schema, tableName) {
+			continue
+		}
+
+		var setting string
+		switch identity {
+		case "f":
+			setting = "FULL"
+		case "n":
+			setting = "NOTHING"
+		case "i":
+			if !index.Valid {
+				continue
+			}
+			setting = "USING INDEX " + QuoteIdentifier(index.String)
+		default:
+			continue
+		}
+
+		results = append(results, fmt.Sprintf("ALTER TABLE ONLY %s.%s REPLICA IDENTITY %s;",
+			QuoteIdentifier(schema),
+			QuoteIdentifier(tableName),
+			setting))
+	}
+
+	return results, rows.Err()
+}
+
+func dumpTriggers(ctx context.Context, db *sql.DB, filter *dumpFilter) ([]SchemaObject, error) {
+	version, err := serverVersionNum(ctx, db)
+	if err != nil {
+		return nil, fmt.Errorf("reading server version: %w", err)
+	}
+	// Since PostgreSQL 13, triggers cloned from a partitioned table's are
+	// not internal, and have tgparentid set
+	clonedExpr := "false"
+	if version >= 130000 {
+		clonedExpr = "t.tgparentid <> 0"
+	}
+
+	query := fmt.Sprintf(`
+		SELECT n.nspname as schema,
+		       c.relname as table_name,
+		       t.tgname as trigger_name,
+		       pg_get_triggerdef(t.oid) as trigger_def,
+		       %s as cloned,
+		       pn.nspname as partition_schema, pc.relname as partition_parent
+		FROM pg_trigger t
+		JOIN pg_class c ON t.tgrelid = c.oid
+		JOIN pg_namespace n ON c.relnamespace = n.oid
+		LEFT JOIN pg_inherits i ON i.inhrelid = c.oid AND c.relispartition
+		LEFT JOIN pg_class pc ON pc.oid = i.inhparent
+		LEFT JOIN pg_namespace pn ON pn.oid = pc.relnamespace
+		WHERE NOT t.tgisinternal
+		  AND n.nspname NOT IN ('pg_catalog', 'information_schema')
+		  AND n.nspname NOT LIKE 'pg_temp_%%'
+		  AND n.nspname NOT LIKE 'pg_toast_temp_%%'
+		ORDER BY n.nspname, c.relname, t.tgname
+	`, clonedExpr)
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []SchemaObject
+	for rows.Next() {
+		var schema, tableName, triggerName, triggerDef string
+		var cloned bool
+		var partitionSchema, partitionParent sql.NullString
+		if err := rows.Scan(&schema, &tableName, &triggerName, &triggerDef,
+			&cloned, &partitionSchema, &partitionParent); err != nil {
+			return nil, err
 		}
-		colRows.Close()
 
-		if len(columns) > 0 {
-			sql := fmt.Sprintf("CREATE TABLE %s.%s (\n    %s\n);",
-				QuoteIdentifier(schema),
-				QuoteIdentifier(tableName),
-				strings.Join(columns, ",\n    "))
-			results = append(results, sql)
+		if !filter.table(schema, tableName) {
+			continue
+		}
+		// Triggers of a partitioned table are created on its partitions too,
+		// except on partitions dumped as standalone tables
+		if cloned && partitionParent.Valid && filter.table(partitionSchema.String, partitionParent.String) {
+			continue
 		}
+
+		results = append(results, SchemaObject{
+			SQL: triggerDef + ";",
+			Drop: fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s.%s;",
+				QuoteIdentifier(triggerName),
+				QuoteIdentifier(schema),
+				QuoteIdentifier(tableName)),
+		})
 	}
 
-	return results, tableRows.Err()
+	return results, rows.Err()
 }
 
-func dumpViews(ctx context.Context, db *sql.DB, excludeSet map[string]bool) ([]string, error) {
+// dumpEventTriggers dumps event triggers. Creating them requires superuser
+// privileges when the migration runs.
+func dumpEventTriggers(ctx context.Context, db *sql.DB, filter *dumpFilter) ([]SchemaObject, error) {
+	tx, err := beginCatalogTx(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
 	query := `
-		SELECT schemaname, viewname, definition
-		FROM pg_views
-		WHERE schemaname NOT IN ('pg_catalog', 'information_schema')
-		  AND schemaname NOT LIKE 'pg_temp_%'
-		  AND schemaname NOT LIKE 'pg_toast_temp_%'
-		ORDER BY schemaname, viewname
+		SELECT e.evtname, e.evtevent, e.evtfoid::regproc::text, fn.nspname,
+		       e.evtenabled::text, e.evttags
+		FROM pg_event_trigger e
+		JOIN pg_proc f ON f.oid = e.evtfoid
+		JOIN pg_namespace fn ON fn.oid = f.pronamespace
+		WHERE NOT EXISTS (
+		    SELECT 1 FROM pg_depend d
+		    WHERE d.classid = 'pg_event_trigger'::regclass
+		      AND d.objid = e.oid
+		      AND d.deptype = 'e'
+		)
+		ORDER BY e.evtname
 	`
 
-	rows, err := db.QueryContext(ctx, query)
+	rows, err := tx.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var results []string
+	var results []SchemaObject
 	for rows.Next() {
-		var schema, name, definition string
-		if err := rows.Scan(&schema, &name, &definition); err != nil {
+		var name, event, function, funcSchema, enabled string
+		var tags []string
+		if err := rows.Scan(&name, &event, &function, &funcSchema, &enabled, pq.Array(&tags)); err != nil {
 			return nil, err
 		}
 
-		fullName := schema + "." + name
-		if excludeSet[fullName] || excludeSet[name] {
+		if !filter.schema(funcSchema) {
 			continue
 		}
 
-		sql := fmt.Sprintf("CREATE VIEW %s.%s AS\n%s",
-			QuoteIdentifier(schema),
-			QuoteIdentifier(name),
-			strings.TrimSuffix(definition, ";"))
-		results = append(results, sql+";")
+		stmt := fmt.Sprintf("CREATE EVENT TRIGGER %s ON %s", QuoteIdentifier(name), QuoteIdentifier(event))
+		if len(tags) > 0 {
+			quoted := make([]string, len(tags))
+			for i, tag := range tags {
+				quoted[i] = QuoteString(tag)
+			}
+			stmt += "\n    WHEN TAG IN (" + strings.Join(quoted, ", ") + ")"
+		}
+		stmt += "\n    EXECUTE FUNCTION " + function + "();"
+		results = append(results, SchemaObject{
+			SQL:  stmt,
+			Drop: fmt.Sprintf("DROP EVENT TRIGGER IF EXISTS %s;", QuoteIdentifier(name)),
+		})
+
+		switch enabled {
+		case "D":
+			results = append(results, SchemaObject{SQL: fmt.Sprintf("ALTER EVENT TRIGGER %s DISABLE;", QuoteIdentifier(name))})
+		case "R":
+			results = append(results, SchemaObject{SQL: fmt.Sprintf("ALTER EVENT TRIGGER %s ENABLE REPLICA;", QuoteIdentifier(name))})
+		case "A":
+			results = append(results, SchemaObject{SQL: fmt.Sprintf("ALTER EVENT TRIGGER %s ENABLE ALWAYS;", QuoteIdentifier(name))})
+		}
 	}
 
 	return results, rows.Err()
 }
 
-func dumpPrimaryKeys(ctx context.Context, db *sql.DB, excludeSet map[string]bool) ([]string, error) {
+// credentialOptions lists user mapping and server options that are never
+// copied into a dump. Their values are replaced by an environment variable
+// reference. Options whose name contains one of credentialParts are treated
+// the same way.
+var credentialOptions = map[string]bool{
+	"user":        true,
+	"username":    true,
+	"password":    true,
+	"passfile":    true,
+	"sslcert":     true,
+	"sslkey":      true,
+	"sslpassword": true,
+}
+
+// credentialParts are name fragments of options that hold secrets, such as
+// api_key, access_token or aws_secret_access_key.
+var credentialParts = []string{"password", "secret", "token", "key", "credential"}
+
+// isCredentialOption reports whether an option holds a credential.
+func isCredentialOption(key string) bool {
+	key = strings.ToLower(key)
+	if credentialOptions[key] {
+		return true
+	}
+	for _, part := range credentialParts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// templateOptions renders options as an OPTIONS clause like genericOptions,
+// replacing credentials with ENVSUB references named after prefix and the
+// option. User names are kept as the default value, other credentials
+// default to empty. It reports whether any option was replaced.
+func templateOptions(options []string, prefix ...string) (string, bool) {
+	if len(options) == 0 {
+		return "", false
+	}
+	templated := false
+	rendered := make([]string, 0, len(options))
+	for _, opt := range options {
+		key, value, _ := strings.Cut(opt, "=")
+		if isCredentialOption(key) {
+			def := ""
+			if key == "user" || key == "username" {
+				def = value
+			}
+			value = fmt.Sprintf("${%s:-%s}", envVarName(append(append([]string{}, prefix...), key)...), def)
+			templated = true
+		}
+		rendered = append(rendered, QuoteIdentifier(key)+" "+QuoteString(value))
+	}
+	return " OPTIONS (" + strings.Join(rendered, ", ") + ")", templated
+}
+
+// foreignScope limits the foreign data wrappers and servers in a dump. A nil
+// scope keeps all of them.
+type foreignScope struct {
+	servers  map[string]bool
+	wrappers map[string]bool
+}
+
+func (s *foreignScope) server(name string) bool {
+	return s == nil || s.servers[name]
+}
+
+func (s *foreignScope) wrapper(name string) bool {
+	return s == nil || s.wrappers[name]
+}
+
+// foreignServersInScope returns the foreign servers and wrappers to dump.
+// Servers don't belong to a schema, so when the dump is limited to some
+// schemas, only the servers of the foreign tables being dumped are kept,
+// with their wrappers (and user mappings). Otherwise it returns nil, and
+// every server is dumped.
+func foreignServersInScope(ctx context.Context, db *sql.DB, filter *dumpFilter) (*foreignScope, error) {
+	if len(filter.schemas) == 0 && len(filter.excludeSchemas) == 0 {
+		return nil, nil
+	}
+
 	query := `
-		SELECT n.nspname as schema, c.relname as table_name,
-		       con.conname as constraint_name,
-		       pg_get_constraintdef(con.oid) as constraint_def
-		FROM pg_constraint con
-		JOIN pg_class c ON con.conrelid = c.oid
-		JOIN pg_namespace n ON c.relnamespace = n.oid
-		WHERE con.contype = 'p'
-		  AND n.nspname NOT IN ('pg_catalog', 'information_schema')
-		  AND n.nspname NOT LIKE 'pg_temp_%'
-		  AND n.nspname NOT LIKE 'pg_toast_temp_%'
-		ORDER BY n.nspname, c.relname, con.conname
+		SELECT n.nspname, c.relname, s.srvname, w.fdwname
+		FROM pg_foreign_table ft
+		JOIN pg_class c ON c.oid = ft.ftrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		JOIN pg_foreign_server s ON s.oid = ft.ftserver
+		JOIN pg_foreign_data_wrapper w ON w.oid = s.srvfdw
 	`
 
 	rows, err := db.QueryContext(ctx, query)
@@ -818,130 +2423,172 @@ func dumpPrimaryKeys(ctx context.Context, db *sql.DB, excludeSet map[string]bool
 	}
 	defer rows.Close()
 
-	var results []string
+	scope := &foreignScope{servers: make(map[string]bool), wrappers: make(map[string]bool)}
 	for rows.Next() {
-		var schema, tableName, conName, conDef string
-		if err := rows.Scan(&schema, &tableName, &conName, &conDef); err != nil {
+		var schema, table, server, wrapper string
+		if err := rows.Scan(&schema, &table, &server, &wrapper); err != nil {
 			return nil, err
 		}
-
-		fullName := schema + "." + tableName
-		if excludeSet[fullName] || excludeSet[tableName] {
-			continue
+		if filter.table(schema, table) {
+			scope.servers[server] = true
+			scope.wrappers[wrapper] = true
 		}
+	}
 
-		sql := fmt.Sprintf("ALTER TABLE %s.%s ADD CONSTRAINT %s %s;",
-			QuoteIdentifier(schema),
-			QuoteIdentifier(tableName),
-			QuoteIdentifier(conName),
-			conDef)
-		results = append(results, sql)
+	return scope, rows.Err()
+}
+
+// genericOptions renders a pg_catalog options array ("key=value" entries)
+// as an OPTIONS clause, or "" when there are none.
+func genericOptions(options []string) string {
+	if len(options) == 0 {
+		return ""
 	}
+	rendered := make([]string, 0, len(options))
+	for _, opt := range options {
+		key, value, _ := strings.Cut(opt, "=")
+		rendered = append(rendered, QuoteIdentifier(key)+" "+QuoteString(value))
+	}
+	return " OPTIONS (" + strings.Join(rendered, ", ") + ")"
+}
 
-	return results, rows.Err()
+// envVarName builds an upper-case environment variable name from parts,
+// replacing anything that isn't a letter or digit with an underscore.
+func envVarName(parts ...string) string {
+	name := strings.ToUpper(strings.Join(parts, "_"))
+	return strings.Map(func(r rune) rune {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
 }
 
-func dumpUniqueConstraints(ctx context.Context, db *sql.DB, excludeSet map[string]bool) ([]string, error) {
+// dumpForeignDataWrappers dumps foreign data wrappers that are not created by
+// an extension (such as postgres_fdw). Wrappers of an extension that the dump
+// leaves out are expected to exist already, like the extension itself.
+func dumpForeignDataWrappers(ctx context.Context, db *sql.DB, scope *foreignScope) ([]SchemaObject, error) {
+	tx, err := beginCatalogTx(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
 	query := `
-		SELECT n.nspname as schema, c.relname as table_name,
-		       con.conname as constraint_name,
-		       pg_get_constraintdef(con.oid) as constraint_def
-		FROM pg_constraint con
-		JOIN pg_class c ON con.conrelid = c.oid
-		JOIN pg_namespace n ON c.relnamespace = n.oid
-		WHERE con.contype = 'u'
-		  AND n.nspname NOT IN ('pg_catalog', 'information_schema')
-		  AND n.nspname NOT LIKE 'pg_temp_%'
-		  AND n.nspname NOT LIKE 'pg_toast_temp_%'
-		ORDER BY n.nspname, c.relname, con.conname
+		SELECT w.fdwname,
+		       CASE WHEN w.fdwhandler <> 0 THEN w.fdwhandler::regproc::text END,
+		       CASE WHEN w.fdwvalidator <> 0 THEN w.fdwvalidator::regproc::text END,
+		       w.fdwoptions
+		FROM pg_foreign_data_wrapper w
+		WHERE NOT EXISTS (
+		    SELECT 1 FROM pg_depend d
+		    WHERE d.classid = 'pg_foreign_data_wrapper'::regclass
+		      AND d.objid = w.oid
+		      AND d.deptype = 'e'
+		)
+		ORDER BY w.fdwname
 	`
 
-	rows, err := db.QueryContext(ctx, query)
+	rows, err := tx.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var results []string
+	var results []SchemaObject
 	for rows.Next() {
-		var schema, tableName, conName, conDef string
-		if err := rows.Scan(&schema, &tableName, &conName, &conDef); err != nil {
+		var name string
+		var handler, validator sql.NullString
+		var options []string
+		if err := rows.Scan(&name, &handler, &validator, pq.Array(&options)); err != nil {
 			return nil, err
 		}
-
-		fullName := schema + "." + tableName
-		if excludeSet[fullName] || excludeSet[tableName] {
+		if !scope.wrapper(name) {
 			continue
 		}
 
-		sql := fmt.Sprintf("ALTER TABLE %s.%s ADD CONSTRAINT %s %s;",
-			QuoteIdentifier(schema),
-			QuoteIdentifier(tableName),
-			QuoteIdentifier(conName),
-			conDef)
-		results = append(results, sql)
+		stmt := "CREATE FOREIGN DATA WRAPPER " + QuoteIdentifier(name)
+		if handler.Valid {
+			stmt += " HANDLER " + handler.String
+		}
+		if validator.Valid {
+			stmt += " VALIDATOR " + validator.String
+		}
+		results = append(results, SchemaObject{
+			SQL:  stmt + genericOptions(options) + ";",
+			Drop: fmt.Sprintf("DROP FOREIGN DATA WRAPPER IF EXISTS %s;", QuoteIdentifier(name)),
+		})
 	}
 
 	return results, rows.Err()
 }
 
-func dumpCheckConstraints(ctx context.Context, db *sql.DB, excludeSet map[string]bool) ([]string, error) {
+// dumpForeignServers dumps foreign servers, templating credential options
+// like dumpUserMappings. It reports whether any option was templated.
+func dumpForeignServers(ctx context.Context, db *sql.DB, scope *foreignScope) ([]SchemaObject, bool, error) {
 	query := `
-		SELECT n.nspname as schema, c.relname as table_name,
-		       con.conname as constraint_name,
-		       pg_get_constraintdef(con.oid) as constraint_def
-		FROM pg_constraint con
-		JOIN pg_class c ON con.conrelid = c.oid
-		JOIN pg_namespace n ON c.relnamespace = n.oid
-		WHERE con.contype = 'c'
-		  AND n.nspname NOT IN ('pg_catalog', 'information_schema')
-		  AND n.nspname NOT LIKE 'pg_temp_%'
-		  AND n.nspname NOT LIKE 'pg_toast_temp_%'
-		ORDER BY n.nspname, c.relname, con.conname
+		SELECT s.srvname, w.fdwname, s.srvtype, s.srvversion, s.srvoptions
+		FROM pg_foreign_server s
+		JOIN pg_foreign_data_wrapper w ON w.oid = s.srvfdw
+		WHERE NOT EXISTS (
+		    SELECT 1 FROM pg_depend d
+		    WHERE d.classid = 'pg_foreign_server'::regclass
+		      AND d.objid = s.oid
+		      AND d.deptype = 'e'
+		)
+		ORDER BY s.srvname
 	`
 
 	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 	defer rows.Close()
 
-	var results []string
+	var results []SchemaObject
+	anyTemplated := false
 	for rows.Next() {
-		var schema, tableName, conName, conDef string
-		if err := rows.Scan(&schema, &tableName, &conName, &conDef); err != nil {
-			return nil, err
+		var name, wrapper string
+		var serverType, serverVersion sql.NullString
+		var options []string
+		if err := rows.Scan(&name, &wrapper, &serverType, &serverVersion, pq.Array(&options)); err != nil {
+			return nil, false, err
 		}
-
-		fullName := schema + "." + tableName
-		if excludeSet[fullName] || excludeSet[tableName] {
+		if !scope.server(name) {
 			continue
 		}
 
-		sql := fmt.Sprintf("ALTER TABLE %s.%s ADD CONSTRAINT %s %s;",
-			QuoteIdentifier(schema),
-			QuoteIdentifier(tableName),
-			QuoteIdentifier(conName),
-			conDef)
-		results = append(results, sql)
+		stmt := "CREATE SERVER " + QuoteIdentifier(name)
+		if serverType.Valid {
+			stmt += " TYPE " + QuoteString(serverType.String)
+		}
+		if serverVersion.Valid {
+			stmt += " VERSION " + QuoteString(serverVersion.String)
+		}
+		stmt += " FOREIGN DATA WRAPPER " + QuoteIdentifier(wrapper)
+		clause, templated := templateOptions(options, "SEEDUP_FDW", name)
+		anyTemplated = anyTemplated || templated
+		results = append(results, SchemaObject{
+			SQL:  stmt + clause + ";",
+			Drop: fmt.Sprintf("DROP SERVER IF EXISTS %s;", QuoteIdentifier(name)),
+		})
 	}
 
-	return results, rows.Err()
+	return results, anyTemplated, rows.Err()
 }
 
-func dumpForeignKeys(ctx context.Context, db *sql.DB, excludeSet map[string]bool) ([]string, error) {
+// dumpUserMappings dumps user mappings with their credentials templated.
+// Credential options (see isCredentialOption) are replaced by goose ENVSUB
+// references such as ${SEEDUP_FDW_ANALYTICS_APP_PASSWORD:-}, so secrets never
+// end up in the migration and each environment supplies its own. The
+// original user name is kept as the default. Options are only visible to the
+// server owner or the mapped user; mappings whose options cannot be read are
+// dumped without them.
+func dumpUserMappings(ctx context.Context, db *sql.DB, scope *foreignScope) ([]SchemaObject, error) {
 	query := `
-		SELECT n.nspname as schema, c.relname as table_name,
-		       con.conname as constraint_name,
-		       pg_get_constraintdef(con.oid) as constraint_def
-		FROM pg_constraint con
-		JOIN pg_class c ON con.conrelid = c.oid
-		JOIN pg_namespace n ON c.relnamespace = n.oid
-		WHERE con.contype = 'f'
-		  AND n.nspname NOT IN ('pg_catalog', 'information_schema')
-		  AND n.nspname NOT LIKE 'pg_temp_%'
-		  AND n.nspname NOT LIKE 'pg_toast_temp_%'
-		ORDER BY n.nspname, c.relname, con.conname
+		SELECT um.srvname, um.usename, um.umoptions
+		FROM pg_user_mappings um
+		ORDER BY um.srvname, um.usename
 	`
 
 	rows, err := db.QueryContext(ctx, query)
@@ -950,104 +2597,334 @@ func dumpForeignKeys(ctx context.Context, db *sql.DB, excludeSet map[string]bool
 	}
 	defer rows.Close()
 
-	var results []string
+	var results []SchemaObject
 	for rows.Next() {
-		var schema, tableName, conName, conDef string
-		if err := rows.Scan(&schema, &tableName, &conName, &conDef); err != nil {
+		var server, user string
+		var options []string
+		if err := rows.Scan(&server, &user, pq.Array(&options)); err != nil {
 			return nil, err
 		}
-
-		fullName := schema + "." + tableName
-		if excludeSet[fullName] || excludeSet[tableName] {
+		if !scope.server(server) {
 			continue
 		}
 
-		sql := fmt.Sprintf("ALTER TABLE %s.%s ADD CONSTRAINT %s %s;",
-			QuoteIdentifier(schema),
-			QuoteIdentifier(tableName),
-			QuoteIdentifier(conName),
-			conDef)
-		results = append(results, sql)
+		role := QuoteIdentifier(user)
+		if user == "public" {
+			role = "PUBLIC"
+		}
+
+		clause, _ := templateOptions(options, "SEEDUP_FDW", server, user)
+		stmt := fmt.Sprintf("CREATE USER MAPPING FOR %s SERVER %s", role, QuoteIdentifier(server))
+		results = append(results, SchemaObject{
+			SQL:  stmt + clause + ";",
+			Drop: fmt.Sprintf("DROP USER MAPPING IF EXISTS FOR %s SERVER %s;", role, QuoteIdentifier(server)),
+		})
 	}
 
 	return results, rows.Err()
 }
 
-func dumpIndexes(ctx context.Context, db *sql.DB, excludeSet map[string]bool) ([]string, error) {
-	// Get indexes that are not backing constraints
-	query := `
-		SELECT schemaname, tablename, indexname, indexdef
-		FROM pg_indexes
-		WHERE schemaname NOT IN ('pg_catalog', 'information_schema')
-		  AND schemaname NOT LIKE 'pg_temp_%'
-		  AND schemaname NOT LIKE 'pg_toast_temp_%'
-		  AND indexname NOT IN (
-		      SELECT conname FROM pg_constraint
-		      WHERE contype IN ('p', 'u', 'x')
+func dumpForeignTables(ctx context.Context, db *sql.DB, filter *dumpFilter) ([]SchemaObject, error) {
+	tx, err := beginCatalogTx(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	tablesQuery := `
+		SELECT c.oid, n.nspname, c.relname, s.srvname, ft.ftoptions
+		FROM pg_foreign_table ft
+		JOIN pg_class c ON c.oid = ft.ftrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		JOIN pg_foreign_server s ON s.oid = ft.ftserver
+		WHERE n.nspname NOT IN ('pg_catalog', 'information_schema')
+		  AND n.nspname NOT LIKE 'pg_temp_%'
+		  AND n.nspname NOT LIKE 'pg_toast_temp_%'
+		  AND NOT EXISTS (
+		      SELECT 1 FROM pg_depend d
+		      WHERE d.classid = 'pg_class'::regclass
+		        AND d.objid = c.oid
+		        AND d.deptype = 'e'
 		  )
-		ORDER BY schemaname, tablename, indexname
+		ORDER BY n.nspname, c.relname
 	`
 
-	rows, err := db.QueryContext(ctx, query)
+	type foreignTable struct {
+		tableRef
+		server  string
+		options []string
+	}
+
+	tableRows, err := tx.QueryContext(ctx, tablesQuery)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	var results []string
-	for rows.Next() {
-		var schema, tableName, indexName, indexDef string
-		if err := rows.Scan(&schema, &tableName, &indexName, &indexDef); err != nil {
+	var tables []foreignTable
+	for tableRows.Next() {
+		var t foreignTable
+		if err := tableRows.Scan(&t.oid, &t.schema, &t.name, &t.server, pq.Array(&t.options)); err != nil {
+			tableRows.Close()
 			return nil, err
 		}
 
-		fullName := schema + "." + tableName
-		if excludeSet[fullName] || excludeSet[tableName] {
+		if !filter.table(t.schema, t.name) {
 			continue
 		}
+		tables = append(tables, t)
+	}
+	tableRows.Close()
+	if err := tableRows.Err(); err != nil {
+		return nil, err
+	}
 
-		results = append(results, indexDef+";")
+	columnsQuery := `
+		SELECT a.attname,
+		       format_type(a.atttypid, a.atttypmod),
+		       a.attnotnull,
+		       pg_get_expr(d.adbin, d.adrelid),
+		       CASE WHEN a.attcollation <> 0 AND a.attcollation <> t.typcollation
+		            THEN quote_ident(cn.nspname) || '.' || quote_ident(co.collname)
+		       END,
+		       a.attfdwoptions
+		FROM pg_attribute a
+		JOIN pg_type t ON t.oid = a.atttypid
+		LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+		LEFT JOIN pg_collation co ON co.oid = a.attcollation
+		LEFT JOIN pg_namespace cn ON cn.oid = co.collnamespace
+		WHERE a.attrelid = $1
+		  AND a.attnum > 0
+		  AND NOT a.attisdropped
+		ORDER BY a.attnum
+	`
+
+	var results []SchemaObject
+	for _, t := range tables {
+		colRows, err := tx.QueryContext(ctx, columnsQuery, t.oid)
+		if err != nil {
+			return nil, fmt.Errorf("querying columns for %s.%s: %w", t.schema, t.name, err)
+		}
+
+		var columns []string
+		for colRows.Next() {
+			var colName, colType string
+			var notNull bool
+			var expr, collation sql.NullString
+			var options []string
+			if err := colRows.Scan(&colName, &colType, &notNull, &expr, &collation, pq.Array(&options)); err != nil {
+				colRows.Close()
+				return nil, fmt.Errorf("scanning column: %w", err)
+			}
+
+			colDef := QuoteIdentifier(colName) + " " + colType + genericOptions(options)
+			if collation.Valid {
+				colDef += " COLLATE " + collation.String
+			}
+			if notNull {
+				colDef += " NOT NULL"
+			}
+			if expr.Valid && expr.String != "" {
+				colDef += " DEFAULT " + expr.String
+			}
+			columns = append(columns, colDef)
+		}
+		colRows.Close()
+		if err := colRows.Err(); err != nil {
+			return nil, fmt.Errorf("iterating columns for %s.%s: %w", t.schema, t.name, err)
+		}
+
+		results = append(results, SchemaObject{
+			SQL: fmt.Sprintf("CREATE FOREIGN TABLE %s.%s (\n    %s\n) SERVER %s%s;",
+				QuoteIdentifier(t.schema),
+				QuoteIdentifier(t.name),
+				strings.Join(columns, ",\n    "),
+				QuoteIdentifier(t.server),
+				genericOptions(t.options)),
+			Drop: dropStatement("FOREIGN TABLE", t.schema, t.name),
+		})
 	}
 
-	return results, rows.Err()
+	return results, nil
 }
 
-func dumpTriggers(ctx context.Context, db *sql.DB, excludeSet map[string]bool) ([]string, error) {
-	query := `
-		SELECT n.nspname as schema,
-		       c.relname as table_name,
-		       t.tgname as trigger_name,
-		       pg_get_triggerdef(t.oid) as trigger_def
-		FROM pg_trigger t
-		JOIN pg_class c ON t.tgrelid = c.oid
-		JOIN pg_namespace n ON c.relnamespace = n.oid
-		WHERE NOT t.tgisinternal
-		  AND n.nspname NOT IN ('pg_catalog', 'information_schema')
-		  AND n.nspname NOT LIKE 'pg_temp_%'
-		  AND n.nspname NOT LIKE 'pg_toast_temp_%'
-		ORDER BY n.nspname, c.relname, t.tgname
-	`
+// dumpPublications dumps logical replication publications. Tables and
+// schemas are added with ALTER PUBLICATION, the way pg_dump does it, and
+// only if they are part of the dump. Column lists, row filters and schema
+// publications require PostgreSQL 15.
+func dumpPublications(ctx context.Context, db *sql.DB, filter *dumpFilter) ([]SchemaObject, error) {
+	version, err := serverVersionNum(ctx, db)
+	if err != nil {
+		return nil, fmt.Errorf("reading server version: %w", err)
+	}
 
-	rows, err := db.QueryContext(ctx, query)
+	viaRootExpr := "false"
+	if version >= 130000 {
+		viaRootExpr = "p.pubviaroot"
+	}
+
+	tx, err := beginCatalogTx(ctx, db)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	defer tx.Rollback()
 
-	var results []string
-	for rows.Next() {
-		var schema, tableName, triggerName, triggerDef string
-		if err := rows.Scan(&schema, &tableName, &triggerName, &triggerDef); err != nil {
+	pubQuery := fmt.Sprintf(`
+		SELECT p.pubname, p.puballtables,
+		       p.pubinsert, p.pubupdate, p.pubdelete, p.pubtruncate,
+		       %s
+		FROM pg_publication p
+		ORDER BY p.pubname
+	`, viaRootExpr)
+
+	type publication struct {
+		name                                      string
+		allTables                                 bool
+		insert, update, delete, truncate, viaRoot bool
+	}
+
+	pubRows, err := tx.QueryContext(ctx, pubQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	var publications []publication
+	for pubRows.Next() {
+		var p publication
+		if err := pubRows.Scan(&p.name, &p.allTables, &p.insert, &p.update, &p.delete,
+			&p.truncate, &p.viaRoot); err != nil {
+			pubRows.Close()
 			return nil, err
 		}
+		publications = append(publications, p)
+	}
+	pubRows.Close()
+	if err := pubRows.Err(); err != nil {
+		return nil, err
+	}
+
+	columnsExpr, whereExpr := "NULL::text[]", "NULL::text"
+	if version >= 150000 {
+		columnsExpr = `CASE WHEN pr.prattrs IS NOT NULL THEN ARRAY(
+		           SELECT quote_ident(a.attname)
+		           FROM unnest(pr.prattrs) WITH ORDINALITY AS k(attnum, ord)
+		           JOIN pg_attribute a ON a.attrelid = pr.prrelid AND a.attnum = k.attnum
+		           ORDER BY k.ord
+		       ) END`
+		whereExpr = "pg_get_expr(pr.prqual, pr.prrelid)"
+	}
+
+	tablesQuery := fmt.Sprintf(`
+		SELECT n.nspname, c.relname, %s, %s
+		FROM pg_publication_rel pr
+		JOIN pg_publication p ON p.oid = pr.prpubid
+		JOIN pg_class c ON c.oid = pr.prrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE p.pubname = $1
+		ORDER BY n.nspname, c.relname
+	`, columnsExpr, whereExpr)
+
+	schemasQuery := `
+		SELECT n.nspname
+		FROM pg_publication_namespace pn
+		JOIN pg_publication p ON p.oid = pn.pnpubid
+		JOIN pg_namespace n ON n.oid = pn.pnnspid
+		WHERE p.pubname = $1
+		ORDER BY n.nspname
+	`
+
+	var results []SchemaObject
+	for _, p := range publications {
+		var actions []string
+		for _, a := range []struct {
+			enabled bool
+			name    string
+		}{{p.insert, "insert"}, {p.update, "update"}, {p.delete, "delete"}, {p.truncate, "truncate"}} {
+			if a.enabled {
+				actions = append(actions, a.name)
+			}
+		}
+
+		stmt := "CREATE PUBLICATION " + QuoteIdentifier(p.name)
+		if p.allTables {
+			stmt += " FOR ALL TABLES"
+		}
+		stmt += " WITH (publish = " + QuoteString(strings.Join(actions, ", "))
+		if p.viaRoot {
+			stmt += ", publish_via_partition_root = true"
+		}
+		results = append(results, SchemaObject{
+			SQL:  stmt + ");",
+			Drop: fmt.Sprintf("DROP PUBLICATION IF EXISTS %s;", QuoteIdentifier(p.name)),
+		})
+
+		if p.allTables {
+			continue
+		}
+
+		tableRows, err := tx.QueryContext(ctx, tablesQuery, p.name)
+		if err != nil {
+			return nil, fmt.Errorf("querying tables for publication %s: %w", p.name, err)
+		}
+		for tableRows.Next() {
+			var schema, tableName string
+			var columns []string
+			var where sql.NullString
+			if err := tableRows.Scan(&schema, &tableName, pq.Array(&columns), &where); err != nil {
+				tableRows.Close()
+				return nil, err
+			}
+
+			if !filter.table(schema, tableName) {
+				continue
+			}
+
+			add := fmt.Sprintf("ALTER PUBLICATION %s ADD TABLE ONLY %s.%s",
+				QuoteIdentifier(p.name),
+				QuoteIdentifier(schema),
+				QuoteIdentifier(tableName))
+			if len(columns) > 0 {
+				add += " (" + strings.Join(columns, ", ") + ")"
+			}
+			if where.Valid {
+				add += " WHERE (" + where.String + ")"
+			}
+			results = append(results, SchemaObject{SQL: add + ";"})
+		}
+		tableRows.Close()
+		if err := tableRows.Err(); err != nil {
+			return nil, fmt.Errorf("iterating tables for publication %s: %w", p.name, err)
+		}
 
-		fullName := schema + "." + tableName
-		if excludeSet[fullName] || excludeSet[tableName] {
+		// Schema publications were added in PostgreSQL 15
+		if version < 150000 {
 			continue
 		}
+		schemaRows, err := tx.QueryContext(ctx, schemasQuery, p.name)
+		if err != nil {
+			return nil, fmt.Errorf("querying schemas for publication %s: %w", p.name, err)
+		}
+		for schemaRows.Next() {
+			var schema string
+			if err := schemaRows.Scan(&schema); err != nil {
+				schemaRows.Close()
+				return nil, err
+			}
+
+			if !filter.schema(schema) {
+				continue
+			}
 
-		results = append(results, triggerDef+";")
+			results = append(results, SchemaObject{
+				SQL: fmt.Sprintf("ALTER PUBLICATION %s ADD TABLES IN SCHEMA %s;",
+					QuoteIdentifier(p.name),
+					QuoteIdentifier(schema)),
+			})
+		}
+		schemaRows.Close()
+		if err := schemaRows.Err(); err != nil {
+			return nil, fmt.Errorf("iterating schemas for publication %s: %w", p.name, err)
+		}
 	}
 
-	return results, rows.Err()
+	return results, nil
 }
@@ -0,0 +1,288 @@
+package pgconn
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+)
+
+// testDB connects to the database in SEEDUP_TEST_DATABASE_URL, skipping the
+// test when it isn't set
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+	dbURL := os.Getenv("SEEDUP_TEST_DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("SEEDUP_TEST_DATABASE_URL not set")
+	}
+	db, err := Open(dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// scratchDB creates an empty database called name next to the one in
+// SEEDUP_TEST_DATABASE_URL, and drops it when the test ends
+func scratchDB(t *testing.T, admin *sql.DB, name string) *sql.DB {
+	t.Helper()
+	drop := "DROP DATABASE IF EXISTS " + QuoteIdentifier(name)
+	mustExec(t, admin, drop)
+	mustExec(t, admin, "CREATE DATABASE "+QuoteIdentifier(name)+" TEMPLATE template0")
+
+	u, err := url.Parse(os.Getenv("SEEDUP_TEST_DATABASE_URL"))
+	if err != nil {
+		t.Fatalf("parsing SEEDUP_TEST_DATABASE_URL: %v", err)
+	}
+	u.Path = "/" + name
+	db, err := Open(u.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		admin.Exec(drop)
+	})
+	return db
+}
+
+// roundTrip runs ddl in an empty database and dumps it, then restores the
+// dump into a second empty database and dumps that. It fails the test if
+// the two dumps differ, and returns the first one.
+func roundTrip(t *testing.T, ddl string) string {
+	t.Helper()
+	ctx := context.Background()
+	admin := testDB(t)
+
+	source := scratchDB(t, admin, "seedup_test_source")
+	mustExec(t, source, ddl)
+	first, err := DumpSchemaWithOptions(ctx, source, DumpOptions{})
+	if err != nil {
+		t.Fatalf("dumping: %v", err)
+	}
+
+	target := scratchDB(t, admin, "seedup_test_target")
+	if _, err := target.ExecContext(ctx, first); err != nil {
+		t.Fatalf("restoring dump: %v\n%s", err, first)
+	}
+	second, err := DumpSchemaWithOptions(ctx, target, DumpOptions{})
+	if err != nil {
+		t.Fatalf("dumping restored schema: %v", err)
+	}
+	if first != second {
+		t.Errorf("dump changed after restore\nfirst:\n%s\nsecond:\n%s", first, second)
+	}
+	return first
+}
+
+func mustExec(t *testing.T, db *sql.DB, query string) {
+	t.Helper()
+	if _, err := db.Exec(query); err != nil {
+		t.Fatalf("%v\n%s", err, query)
+	}
+}
+
+func TestDumpConstraintsRoundTrip(t *testing.T) {
+	dump := roundTrip(t, `
+		CREATE SCHEMA seedup_constraints;
+
+		CREATE TABLE seedup_constraints.accounts (
+			id integer NOT NULL,
+			email text,
+			code text,
+			balance integer,
+			CONSTRAINT accounts_pkey PRIMARY KEY (id),
+			CONSTRAINT accounts_email_key UNIQUE (email),
+			CONSTRAINT accounts_code_key UNIQUE (code) DEFERRABLE INITIALLY DEFERRED,
+			CONSTRAINT accounts_balance_check CHECK (balance >= 0) NO INHERIT
+		);
+		ALTER TABLE seedup_constraints.accounts
+			ADD CONSTRAINT accounts_code_check CHECK (code <> '') NOT VALID;
+
+		CREATE TABLE seedup_constraints.bookings (
+			id integer PRIMARY KEY,
+			account_id integer,
+			during tsrange,
+			CONSTRAINT bookings_no_overlap EXCLUDE USING gist (during WITH &&),
+			CONSTRAINT bookings_account_id_fkey FOREIGN KEY (account_id)
+				REFERENCES seedup_constraints.accounts (id)
+				ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED
+		);
+	`)
+
+	for _, want := range []string{
+		`ADD CONSTRAINT "accounts_pkey" PRIMARY KEY (id);`,
+		`ADD CONSTRAINT "accounts_email_key" UNIQUE (email);`,
+		`ADD CONSTRAINT "accounts_code_key" UNIQUE (code) DEFERRABLE INITIALLY DEFERRED;`,
+		`ADD CONSTRAINT "accounts_balance_check" CHECK ((balance >= 0)) NO INHERIT;`,
+		`ADD CONSTRAINT "accounts_code_check" CHECK ((code <> ''::text)) NOT VALID;`,
+		`ADD CONSTRAINT "bookings_no_overlap" EXCLUDE USING gist (during WITH &&);`,
+		`ADD CONSTRAINT "bookings_account_id_fkey" FOREIGN KEY (account_id) REFERENCES seedup_constraints.accounts(id) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED;`,
+	} {
+		if !strings.Contains(dump, want) {
+			t.Errorf("dump is missing %q\n%s", want, dump)
+		}
+	}
+}
+
+func TestDumpColumnTypes(t *testing.T) {
+	tests := []struct {
+		name    string
+		types   string // types the column needs
+		columns string
+		want    string
+	}{
+		{
+			name:    "integer array",
+			columns: "c integer[]",
+			want:    `"c" integer[]`,
+		},
+		{
+			name:    "varchar array",
+			columns: "c varchar(50)[]",
+			want:    `"c" character varying(50)[]`,
+		},
+		{
+			name:    "domain",
+			types:   "CREATE DOMAIN seedup_types.positive AS integer CHECK (VALUE > 0);",
+			columns: "c seedup_types.positive",
+			want:    `"c" seedup_types.positive`,
+		},
+		{
+			name:    "enum",
+			types:   "CREATE TYPE seedup_types.mood AS ENUM ('sad', 'happy');",
+			columns: "c seedup_types.mood NOT NULL DEFAULT 'happy'",
+			want:    `"c" seedup_types.mood NOT NULL DEFAULT 'happy'::seedup_types.mood`,
+		},
+		{
+			name:    "numeric with precision and scale",
+			columns: "c numeric(10,2)",
+			want:    `"c" numeric(10,2)`,
+		},
+		{
+			name:    "varchar",
+			columns: "c varchar(20)",
+			want:    `"c" character varying(20)`,
+		},
+		{
+			name:    "timestamptz",
+			columns: "c timestamptz",
+			want:    `"c" timestamp with time zone`,
+		},
+		{
+			name:    "timestamptz with precision",
+			columns: "c timestamptz(3)",
+			want:    `"c" timestamp(3) with time zone`,
+		},
+		{
+			name:    "identity",
+			columns: "c bigint GENERATED ALWAYS AS IDENTITY",
+			want:    `"c" bigint GENERATED ALWAYS AS IDENTITY (SEQUENCE NAME "seedup_types"."t_c_seq"`,
+		},
+		{
+			name:    "generated",
+			columns: "a integer, c integer GENERATED ALWAYS AS (a * 2) STORED",
+			want:    `"c" integer GENERATED ALWAYS AS ((a * 2)) STORED`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dump := roundTrip(t, "CREATE SCHEMA seedup_types;\n"+tt.types+"\nCREATE TABLE seedup_types.t ("+tt.columns+");")
+			if !strings.Contains(dump, tt.want) {
+				t.Errorf("dump is missing %q\n%s", tt.want, dump)
+			}
+		})
+	}
+}
+
+func TestDumpPartitionedTableRoundTrip(t *testing.T) {
+	dump := roundTrip(t, `
+		CREATE SCHEMA seedup_partitions;
+
+		CREATE TABLE seedup_partitions.accounts (id integer PRIMARY KEY);
+
+		CREATE TABLE seedup_partitions.events (
+			id integer NOT NULL,
+			account_id integer REFERENCES seedup_partitions.accounts (id),
+			kind text NOT NULL DEFAULT 'click',
+			created_at date NOT NULL,
+			PRIMARY KEY (id, created_at),
+			CHECK (kind <> '')
+		) PARTITION BY RANGE (created_at);
+		CREATE INDEX events_kind_idx ON seedup_partitions.events (kind);
+
+		CREATE TABLE seedup_partitions.events_2025 PARTITION OF seedup_partitions.events
+			FOR VALUES FROM ('2025-01-01') TO ('2026-01-01');
+		CREATE TABLE seedup_partitions.events_2026 PARTITION OF seedup_partitions.events
+			FOR VALUES FROM ('2026-01-01') TO ('2027-01-01')
+			PARTITION BY LIST (kind);
+		CREATE TABLE seedup_partitions.events_2026_clicks PARTITION OF seedup_partitions.events_2026
+			FOR VALUES IN ('click');
+		CREATE TABLE seedup_partitions.events_default PARTITION OF seedup_partitions.events DEFAULT;
+	`)
+
+	for _, want := range []string{
+		`) PARTITION BY RANGE (created_at);`,
+		`CREATE TABLE "seedup_partitions"."events_2025" PARTITION OF "seedup_partitions"."events" FOR VALUES FROM ('2025-01-01') TO ('2026-01-01');`,
+		`CREATE TABLE "seedup_partitions"."events_2026" PARTITION OF "seedup_partitions"."events" FOR VALUES FROM ('2026-01-01') TO ('2027-01-01') PARTITION BY LIST (kind);`,
+		`CREATE TABLE "seedup_partitions"."events_2026_clicks" PARTITION OF "seedup_partitions"."events_2026" FOR VALUES IN ('click');`,
+		`CREATE TABLE "seedup_partitions"."events_default" PARTITION OF "seedup_partitions"."events" DEFAULT;`,
+	} {
+		if !strings.Contains(dump, want) {
+			t.Errorf("dump is missing %q\n%s", want, dump)
+		}
+	}
+	if strings.Contains(dump, `ALTER TABLE "seedup_partitions"."events_2025" ADD CONSTRAINT`) {
+		t.Errorf("dump repeats constraints cloned onto a partition\n%s", dump)
+	}
+}
+
+func TestTemplateOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		options   []string
+		want      string
+		templated bool
+	}{
+		{
+			name:    "no credentials",
+			options: []string{"host=db.internal", "dbname=analytics", "fetch_size=500"},
+			want:    ` OPTIONS ("host" 'db.internal', "dbname" 'analytics', "fetch_size" '500')`,
+		},
+		{
+			name:      "user and password",
+			options:   []string{"user=reporting", "password=hunter2"},
+			want:      ` OPTIONS ("user" '${SEEDUP_FDW_ANALYTICS_APP_USER:-reporting}', "password" '${SEEDUP_FDW_ANALYTICS_APP_PASSWORD:-}')`,
+			templated: true,
+		},
+		{
+			name:      "credential-like names",
+			options:   []string{"sslkey=/etc/key.pem", "aws_secret_access_key=abc", "api_token=xyz", "passfile=/root/.pgpass"},
+			want:      ` OPTIONS ("sslkey" '${SEEDUP_FDW_ANALYTICS_APP_SSLKEY:-}', "aws_secret_access_key" '${SEEDUP_FDW_ANALYTICS_APP_AWS_SECRET_ACCESS_KEY:-}', "api_token" '${SEEDUP_FDW_ANALYTICS_APP_API_TOKEN:-}', "passfile" '${SEEDUP_FDW_ANALYTICS_APP_PASSFILE:-}')`,
+			templated: true,
+		},
+		{
+			name:    "none",
+			options: nil,
+			want:    "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, templated := templateOptions(tt.options, "SEEDUP_FDW", "analytics", "app")
+			if got != tt.want || templated != tt.templated {
+				t.Errorf("templateOptions() = %q, %v; want %q, %v", got, templated, tt.want, tt.templated)
+			}
+			for _, opt := range tt.options {
+				if _, secret, _ := strings.Cut(opt, "="); tt.templated && secret != "reporting" && strings.Contains(got, secret) {
+					t.Errorf("templateOptions() leaks %q: %s", secret, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package pgconn
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SchemaObject is a single statement of a schema dump.
+type SchemaObject struct {
+	// SQL creates the object, or alters one created earlier in the dump.
+	SQL string
+
+	// Drop removes what SQL created. It is empty for statements that only
+	// alter another object, such as sequence ownership or replica identity,
+	// since those go away with the object they alter.
+	Drop string
+}
+
+// dumpSection is a group of objects of the same kind, in creation order.
+type dumpSection struct {
+	header  string
+	objects []SchemaObject
+	envsub  bool // wrap the statements in a goose ENVSUB block
+}
+
+// SchemaDump is the list of objects produced by [DumpSchemaObjects], in the
+// order they must be created.
+type SchemaDump struct {
+	sections []dumpSection
+}
+
+// add appends a section, skipping empty ones.
+func (d *SchemaDump) add(header string, objects []SchemaObject) {
+	if len(objects) > 0 {
+		d.sections = append(d.sections, dumpSection{header: header, objects: objects})
+	}
+}
+
+// addEnvsub appends a section whose statements reference environment
+// variables, which goose substitutes only inside an ENVSUB block.
+func (d *SchemaDump) addEnvsub(header string, objects []SchemaObject) {
+	if len(objects) > 0 {
+		d.sections = append(d.sections, dumpSection{header: header, objects: objects, envsub: true})
+	}
+}
+
+// Objects returns every dumped object in creation order.
+func (d *SchemaDump) Objects() []SchemaObject {
+	var objects []SchemaObject
+	for _, section := range d.sections {
+		objects = append(objects, section.objects...)
+	}
+	return objects
+}
+
+// SQL returns the statements that create the schema.
+func (d *SchemaDump) SQL() string {
+	var parts []string
+	for _, section := range d.sections {
+		parts = append(parts, "-- "+section.header)
+		if section.envsub {
+			parts = append(parts, "-- +goose ENVSUB ON")
+		}
+		for _, obj := range section.objects {
+			parts = append(parts, obj.SQL)
+		}
+		if section.envsub {
+			parts = append(parts, "-- +goose ENVSUB OFF")
+		}
+		parts = append(parts, "")
+	}
+	return strings.Join(parts, "\n")
+}
+
+// DropSQL returns the statements that remove the schema, dropping every
+// object in the reverse of its creation order so that nothing is dropped
+// while another object still depends on it. Every statement uses IF EXISTS,
+// because some objects (such as serial sequences, or indexes of a
+// materialized view) already went away with an object dropped before them.
+func (d *SchemaDump) DropSQL() string {
+	var parts []string
+	for i := len(d.sections) - 1; i >= 0; i-- {
+		section := d.sections[i]
+
+		var drops []string
+		for j := len(section.objects) - 1; j >= 0; j-- {
+			if drop := section.objects[j].Drop; drop != "" {
+				drops = append(drops, drop)
+			}
+		}
+		if len(drops) == 0 {
+			continue
+		}
+
+		parts = append(parts, "-- "+section.header)
+		parts = append(parts, drops...)
+		parts = append(parts, "")
+	}
+	return strings.Join(parts, "\n")
+}
+
+// statements wraps statements that have no drop of their own.
+func statements(stmts []string) []SchemaObject {
+	objects := make([]SchemaObject, len(stmts))
+	for i, stmt := range stmts {
+		objects[i] = SchemaObject{SQL: stmt}
+	}
+	return objects
+}
+
+// dropStatement renders DROP <kind> IF EXISTS for a schema-qualified object.
+func dropStatement(kind, schema, name string) string {
+	return fmt.Sprintf("DROP %s IF EXISTS %s.%s;", kind, QuoteIdentifier(schema), QuoteIdentifier(name))
+}
+
+// dropRoutineStatement renders DROP FUNCTION or DROP PROCEDURE for a routine
+// identified by its argument types, as given by
+// pg_get_function_identity_arguments.
+func dropRoutineStatement(schema, name, identityArgs string, procedure bool) string {
+	kind := "FUNCTION"
+	if procedure {
+		kind = "PROCEDURE"
+	}
+	return fmt.Sprintf("DROP %s IF EXISTS %s.%s(%s);", kind, QuoteIdentifier(schema), QuoteIdentifier(name), identityArgs)
+}
@@ -0,0 +1,74 @@
+package pgconn
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDropSQL(t *testing.T) {
+	var d SchemaDump
+	d.add("Schemas", []SchemaObject{
+		{SQL: `CREATE SCHEMA "app";`, Drop: `DROP SCHEMA IF EXISTS "app";`},
+	})
+	d.add("Empty", nil)
+	d.add("Sequences", []SchemaObject{
+		{SQL: `CREATE SEQUENCE "app"."users_id_seq";`, Drop: dropStatement("SEQUENCE", "app", "users_id_seq")},
+	})
+	d.add("Tables", []SchemaObject{
+		{SQL: `CREATE TABLE "app"."users" ();`, Drop: dropStatement("TABLE", "app", "users")},
+		{SQL: `CREATE TABLE "app"."posts" ();`, Drop: dropStatement("TABLE", "app", "posts")},
+		{SQL: `ALTER SEQUENCE "app"."users_id_seq" OWNED BY "app"."users"."id";`},
+	})
+	d.add("Alterations", []SchemaObject{
+		{SQL: `ALTER TABLE "app"."users" REPLICA IDENTITY FULL;`},
+	})
+	d.add("Functions", []SchemaObject{
+		{SQL: `CREATE FUNCTION "app"."touch"(integer, text) ...;`, Drop: dropRoutineStatement("app", "touch", "integer, text", false)},
+		{SQL: `CREATE PROCEDURE "app"."purge"() ...;`, Drop: dropRoutineStatement("app", "purge", "", true)},
+	})
+
+	want := strings.Join([]string{
+		"-- Functions",
+		`DROP PROCEDURE IF EXISTS "app"."purge"();`,
+		`DROP FUNCTION IF EXISTS "app"."touch"(integer, text);`,
+		"",
+		"-- Tables",
+		`DROP TABLE IF EXISTS "app"."posts";`,
+		`DROP TABLE IF EXISTS "app"."users";`,
+		"",
+		"-- Sequences",
+		`DROP SEQUENCE IF EXISTS "app"."users_id_seq";`,
+		"",
+		"-- Schemas",
+		`DROP SCHEMA IF EXISTS "app";`,
+		"",
+	}, "\n")
+	if got := d.DropSQL(); got != want {
+		t.Errorf("DropSQL() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestDropSQLEmpty(t *testing.T) {
+	var d SchemaDump
+	d.add("Alterations", []SchemaObject{{SQL: `ALTER TABLE "app"."users" REPLICA IDENTITY FULL;`}})
+	if got := d.DropSQL(); got != "" {
+		t.Errorf("DropSQL() = %q, want nothing for statements without drops", got)
+	}
+}
+
+func TestDropStatement(t *testing.T) {
+	tests := []struct {
+		got, want string
+	}{
+		{dropStatement("TABLE", "public", "users"), `DROP TABLE IF EXISTS "public"."users";`},
+		{dropStatement("MATERIALIZED VIEW", "app", "Daily Stats"), `DROP MATERIALIZED VIEW IF EXISTS "app"."Daily Stats";`},
+		{dropStatement("TYPE", "app", `say "hi"`), `DROP TYPE IF EXISTS "app"."say ""hi""";`},
+		{dropRoutineStatement("public", "add", "a integer, b integer", false), `DROP FUNCTION IF EXISTS "public"."add"(a integer, b integer);`},
+		{dropRoutineStatement("public", "cleanup", "", true), `DROP PROCEDURE IF EXISTS "public"."cleanup"();`},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("got %s, want %s", tt.got, tt.want)
+		}
+	}
+}
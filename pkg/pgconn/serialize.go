@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"math"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -142,9 +144,23 @@ func SerializeValue(value interface{}, pgType string) string {
 		normalizedType == "serial" || normalizedType == "bigserial":
 		return fmt.Sprintf("%v", value)
 
-	// Floating point types
-	case normalizedType == "real" || normalizedType == "double precision" ||
-		normalizedType == "numeric" || normalizedType == "decimal" ||
+	// Floating point types. Floats use the shortest representation that
+	// reads back as the same value; NaN and infinities must be quoted.
+	case normalizedType == "real" || normalizedType == "double precision":
+		if f, ok := value.(float64); ok {
+			// lib/pq reads real values as float32 precision
+			bitSize := 64
+			if normalizedType == "real" {
+				bitSize = 32
+			}
+			if math.IsNaN(f) || math.IsInf(f, 0) {
+				return QuoteString(formatFloat(f, bitSize))
+			}
+			return formatFloat(f, bitSize)
+		}
+		return fmt.Sprintf("%v", value)
+
+	case normalizedType == "numeric" || normalizedType == "decimal" ||
 		strings.HasPrefix(normalizedType, "numeric("):
 		return fmt.Sprintf("%v", value)
 
@@ -160,8 +176,9 @@ func SerializeValue(value interface{}, pgType string) string {
 	case normalizedType == "timestamp with time zone" ||
 		normalizedType == "timestamptz" ||
 		strings.HasPrefix(normalizedType, "timestamp(") && strings.Contains(normalizedType, "with time zone"):
+		// Written in UTC, so the output doesn't depend on the session time zone
 		if t, ok := value.(time.Time); ok {
-			return QuoteString(t.Format("2006-01-02 15:04:05.999999-07:00"))
+			return QuoteString(t.UTC().Format("2006-01-02 15:04:05.999999") + "+00")
 		}
 		return QuoteString(fmt.Sprintf("%v", value))
 
@@ -255,6 +272,20 @@ func SerializeValue(value interface{}, pgType string) string {
 	}
 }
 
+// formatFloat formats a float of the given bit size the way Postgres reads
+// it back
+func formatFloat(f float64, bitSize int) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	}
+	return strconv.FormatFloat(f, 'g', -1, bitSize)
+}
+
 // QuoteString properly escapes a string for PostgreSQL SQL literals.
 func QuoteString(s string) string {
 	// Check if we need E'' syntax for escape sequences
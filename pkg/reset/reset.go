@@ -0,0 +1,211 @@
+// Package reset rebuilds a development database in one go: setup, seed,
+// migrate and, optionally, snapshot.
+package reset
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lucasefe/seedup/pkg/db"
+	"github.com/lucasefe/seedup/pkg/migrate"
+	"github.com/lucasefe/seedup/pkg/seed"
+)
+
+// Options configures Run
+type Options struct {
+	// DatabaseURL is the database to rebuild.
+	DatabaseURL string
+	// AdminURL is the connection URL for admin operations. Defaults to the
+	// current system user connecting to the postgres database.
+	AdminURL string
+	// MigrationsDir is the migrations directory.
+	MigrationsDir string
+	// SeedDir is the seed set to apply, e.g. ./seed/dev.
+	SeedDir string
+	// SkipTestDB skips creating the test database during setup.
+	SkipTestDB bool
+	// Apply configures how the seed is loaded.
+	Apply seed.ApplyOptions
+
+	// Snapshot saves the rebuilt database as <db>_snapshot.
+	Snapshot bool
+	// FromSnapshot restores the database from <db>_snapshot instead of
+	// running the pipeline, when the snapshot exists.
+	FromSnapshot bool
+
+	// Restart runs every step, ignoring the progress of a failed earlier
+	// run. By default, a run after a failure resumes at the failed step.
+	Restart bool
+	// StateFile records the steps completed so far. Defaults to a file
+	// per database under the user cache directory.
+	StateFile string
+}
+
+// step is one stage of the pipeline
+type step struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+// state is the progress saved between runs
+type state struct {
+	Database  string   `json:"database"`
+	SeedDir   string   `json:"seed_dir"`
+	Completed []string `json:"completed"`
+}
+
+// Run rebuilds the database: db setup, seed apply, migrate up and, with
+// opts.Snapshot, a snapshot of the result. Each step prints its duration.
+// If a step fails, the next run resumes at that step.
+func Run(ctx context.Context, opts Options) error {
+	cfg, err := db.ParseDatabaseURL(opts.DatabaseURL)
+	if err != nil {
+		return err
+	}
+	manager := db.New()
+
+	if opts.FromSnapshot {
+		exists, err := manager.SnapshotExists(ctx, opts.DatabaseURL, opts.AdminURL)
+		if err != nil {
+			return err
+		}
+		if exists {
+			fmt.Printf("Restoring '%s' from snapshot '%s'...\n", cfg.Database, db.SnapshotName(cfg.Database))
+			started := time.Now()
+			if err := manager.Restore(ctx, opts.DatabaseURL, opts.AdminURL); err != nil {
+				return err
+			}
+			fmt.Printf("Restored in %s\n", time.Since(started).Round(time.Millisecond))
+			return nil
+		}
+		fmt.Printf("No snapshot of '%s' found, running the full pipeline\n", cfg.Database)
+	}
+
+	steps := []step{
+		{"setup", func(ctx context.Context) error {
+			return manager.Setup(ctx, db.SetupOptions{
+				DatabaseURL: opts.DatabaseURL,
+				AdminURL:    opts.AdminURL,
+				SkipTestDB:  opts.SkipTestDB,
+			})
+		}},
+		{"seed", func(ctx context.Context) error {
+			return seed.New().ApplyWithOptions(ctx, opts.DatabaseURL, opts.MigrationsDir, opts.SeedDir, opts.Apply)
+		}},
+		{"migrate", func(ctx context.Context) error {
+			return migrate.New().Up(ctx, opts.DatabaseURL, opts.MigrationsDir)
+		}},
+	}
+	if opts.Snapshot {
+		steps = append(steps, step{"snapshot", func(ctx context.Context) error {
+			return manager.Snapshot(ctx, opts.DatabaseURL, opts.AdminURL)
+		}})
+	}
+
+	stateFile := opts.StateFile
+	if stateFile == "" {
+		if stateFile, err = defaultStateFile(cfg); err != nil {
+			return err
+		}
+	}
+
+	current := state{Database: cfg.Database, SeedDir: opts.SeedDir}
+	if !opts.Restart {
+		current.Completed = resumedSteps(stateFile, current)
+	}
+	done := make(map[string]bool)
+	for _, name := range current.Completed {
+		done[name] = true
+	}
+
+	durations := make([]time.Duration, len(steps))
+	started := time.Now()
+	for i, s := range steps {
+		label := fmt.Sprintf("[%d/%d] %s", i+1, len(steps), s.name)
+		if done[s.name] {
+			fmt.Printf("==> %s (completed in a previous run, skipping)\n", label)
+			continue
+		}
+
+		fmt.Printf("==> %s\n", label)
+		stepStarted := time.Now()
+		if err := s.run(ctx); err != nil {
+			return fmt.Errorf("%s failed (run reset again to resume, or use --restart): %w", s.name, err)
+		}
+		durations[i] = time.Since(stepStarted)
+		fmt.Printf("    %s done in %s\n", s.name, durations[i].Round(time.Millisecond))
+
+		current.Completed = append(current.Completed, s.name)
+		if err := saveState(stateFile, current); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println("\nReset summary:")
+	for i, s := range steps {
+		if done[s.name] {
+			fmt.Printf("  %-10s skipped (resumed)\n", s.name)
+			continue
+		}
+		fmt.Printf("  %-10s %s\n", s.name, durations[i].Round(time.Millisecond))
+	}
+	fmt.Printf("  %-10s %s\n", "total", time.Since(started).Round(time.Millisecond))
+
+	if err := os.Remove(stateFile); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing reset state: %w", err)
+	}
+	return nil
+}
+
+// defaultStateFile returns the state file for cfg's database under the user
+// cache directory
+func defaultStateFile(cfg *db.DBConfig) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("finding cache directory: %w", err)
+	}
+	name := fmt.Sprintf("reset-%s-%s-%s.json", cfg.Host, cfg.Port, cfg.Database)
+	return filepath.Join(dir, "seedup", name), nil
+}
+
+// resumedSteps returns the steps completed by an earlier run of the same
+// database and seed set. A run for anything else starts over.
+func resumedSteps(path string, current state) []string {
+	previous, ok := loadState(path)
+	if !ok || previous.Database != current.Database || previous.SeedDir != current.SeedDir {
+		return nil
+	}
+	return previous.Completed
+}
+
+// loadState reads the progress of an earlier run, if there is one
+func loadState(path string) (state, bool) {
+	var s state
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return s, false
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return s, false
+	}
+	return s, true
+}
+
+// saveState records the progress of the current run
+func saveState(path string, s state) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("creating reset state directory: %w", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding reset state: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("writing reset state: %w", err)
+	}
+	return nil
+}
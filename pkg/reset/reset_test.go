@@ -0,0 +1,61 @@
+package reset
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestResumedSteps(t *testing.T) {
+	saved := state{Database: "myapp", SeedDir: "seed/dev", Completed: []string{"setup", "seed"}}
+
+	tests := []struct {
+		name    string
+		current state
+		want    []string
+	}{
+		{
+			name:    "same database and seed",
+			current: state{Database: "myapp", SeedDir: "seed/dev"},
+			want:    []string{"setup", "seed"},
+		},
+		{
+			name:    "other database",
+			current: state{Database: "otherapp", SeedDir: "seed/dev"},
+		},
+		{
+			name:    "other seed",
+			current: state{Database: "myapp", SeedDir: "seed/e2e"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "seedup", "reset.json")
+			if err := saveState(path, saved); err != nil {
+				t.Fatal(err)
+			}
+			if got := resumedSteps(path, tt.current); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resumedSteps() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResumedStepsWithoutState(t *testing.T) {
+	current := state{Database: "myapp", SeedDir: "seed/dev"}
+
+	missing := filepath.Join(t.TempDir(), "missing.json")
+	if got := resumedSteps(missing, current); got != nil {
+		t.Errorf("resumedSteps() without a state file = %v, want nil", got)
+	}
+
+	corrupt := filepath.Join(t.TempDir(), "corrupt.json")
+	if err := os.WriteFile(corrupt, []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := resumedSteps(corrupt, current); got != nil {
+		t.Errorf("resumedSteps() with a corrupt state file = %v, want nil", got)
+	}
+}
@@ -1,9 +1,11 @@
 package seed
 
 import (
+	"bufio"
 	"context"
 	"database/sql"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
@@ -12,27 +14,114 @@ import (
 	"github.com/lucasefe/seedup/pkg/pgconn"
 )
 
+// ApplyMode selects how seed data is loaded
+type ApplyMode string
+
+const (
+	// ModeTruncate truncates every seeded table (with CASCADE) before
+	// loading. This is the default.
+	ModeTruncate ApplyMode = "truncate"
+	// ModeMerge upserts the seed rows on their primary key, keeping rows
+	// that are not in the seed
+	ModeMerge ApplyMode = "merge"
+)
+
+// ConflictAction is what ModeMerge does with a seed row whose primary key
+// already exists
+type ConflictAction string
+
+const (
+	// ConflictUpdate overwrites the existing row with the seed's values.
+	// This is the default.
+	ConflictUpdate ConflictAction = "update"
+	// ConflictNothing keeps the existing row
+	ConflictNothing ConflictAction = "nothing"
+)
+
+// ApplyOptions configures how seed data is applied
+type ApplyOptions struct {
+	// Mode selects truncate (default) or merge loading.
+	Mode ApplyMode
+	// OnConflict is the merge action for rows that already exist.
+	// Only used with ModeMerge; defaults to ConflictUpdate.
+	OnConflict ConflictAction
+	// Triggers selects how triggers are disabled while loading.
+	// Defaults to TriggersAuto.
+	Triggers TriggerStrategy
+	// Tables limits loading to the tables matching one of these glob
+	// patterns, in the form of pgconn.DumpOptions.IncludeTables. Empty
+	// means all tables in the seed.
+	Tables []string
+	// ExcludeTables lists tables of the seed to leave alone, as glob
+	// patterns in the same form as Tables.
+	ExcludeTables []string
+}
+
 // Apply seeds the database with data from SQL files.
 // It runs the initial migration (to establish the schema the seed was created against),
 // then loads seed data. Run 'migrate up' separately to apply remaining migrations.
+//
+// If seedDir contains an ExtendsFile, the seed set it names is loaded first,
+// followed by seedDir's own data.
 func (s *Seeder) Apply(ctx context.Context, dbURL, migrationsDir, seedDir string) error {
+	return s.ApplyWithOptions(ctx, dbURL, migrationsDir, seedDir, ApplyOptions{})
+}
+
+// ApplyWithOptions is like Apply but accepts options controlling how the
+// seed data is loaded
+func (s *Seeder) ApplyWithOptions(ctx context.Context, dbURL, migrationsDir, seedDir string, opts ApplyOptions) error {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return err
+	}
+
 	// Run the initial migration (schema at point of creating seed)
 	// Use UpByOneAllowNoop to handle the case where migrations are already applied
-	fmt.Println("Running initial migration (if pending)...")
-	if err := s.migrator.UpByOneAllowNoop(ctx, dbURL, migrationsDir); err != nil {
+	fmt.Fprintln(s.stdout, "Running initial migration (if pending)...")
+	migrateMu.Lock()
+	err = s.migrator.UpByOneAllowNoop(ctx, dbURL, migrationsDir)
+	migrateMu.Unlock()
+	if err != nil {
 		return fmt.Errorf("running initial migration: %w", err)
 	}
 
 	// Build and execute the seed script
-	fmt.Println("Seeding database...")
-	if err := s.loadSeedData(ctx, dbURL, seedDir); err != nil {
+	fmt.Fprintln(s.stdout, "Seeding database...")
+	if err := s.loadSeedData(ctx, dbURL, seedDir, opts); err != nil {
 		return fmt.Errorf("loading seed data: %w", err)
 	}
 
 	return nil
 }
 
-func (s *Seeder) loadSeedData(ctx context.Context, dbURL, seedDir string) error {
+// withDefaults validates the options and fills in the defaults
+func (opts ApplyOptions) withDefaults() (ApplyOptions, error) {
+	switch opts.Mode {
+	case "":
+		opts.Mode = ModeTruncate
+	case ModeTruncate, ModeMerge:
+	default:
+		return opts, fmt.Errorf("unknown seed apply mode %q (use %q or %q)", opts.Mode, ModeTruncate, ModeMerge)
+	}
+	switch opts.Triggers {
+	case "":
+		opts.Triggers = TriggersAuto
+	case TriggersAuto, TriggersReplicationRole, TriggersDisableTrigger, TriggersDeferConstraints:
+	default:
+		return opts, fmt.Errorf("unknown trigger strategy %q (use %q, %q, %q or %q)", opts.Triggers,
+			TriggersAuto, TriggersReplicationRole, TriggersDisableTrigger, TriggersDeferConstraints)
+	}
+	switch opts.OnConflict {
+	case "":
+		opts.OnConflict = ConflictUpdate
+	case ConflictUpdate, ConflictNothing:
+	default:
+		return opts, fmt.Errorf("unknown conflict action %q (use %q or %q)", opts.OnConflict, ConflictUpdate, ConflictNothing)
+	}
+	return opts, nil
+}
+
+func (s *Seeder) loadSeedData(ctx context.Context, dbURL, seedDir string, opts ApplyOptions) error {
 	// Open database connection
 	db, err := pgconn.Open(dbURL)
 	if err != nil {
@@ -40,33 +129,37 @@ func (s *Seeder) loadSeedData(ctx context.Context, dbURL, seedDir string) error
 	}
 	defer db.Close()
 
-	// Look for load.sql (new format)
-	seedFile := filepath.Join(seedDir, "load.sql")
-	content, err := os.ReadFile(seedFile)
+	// Resolve the extends chain, base first
+	dirs, err := resolveSeedLayers(seedDir)
 	if err != nil {
-		if os.IsNotExist(err) {
-			// Check for legacy per-table files
-			legacyFiles, _ := filepath.Glob(filepath.Join(seedDir, "*.*.sql"))
-			if len(legacyFiles) > 0 {
-				return fmt.Errorf("found legacy per-table SQL files but no load.sql. Please run 'seed create' to regenerate seed files in the new format")
-			}
-			fmt.Println("No load.sql found in seed directory")
-			return nil
-		}
-		return fmt.Errorf("reading seed file %s: %w", seedFile, err)
+		return err
 	}
 
-	// Extract table names from comments (e.g., "-- Table: public.users")
+	var layers []seedLayer
 	var tables []string
-	for _, line := range strings.Split(string(content), "\n") {
-		if strings.HasPrefix(line, "-- Table: ") {
-			tableName := strings.TrimPrefix(line, "-- Table: ")
-			tables = append(tables, tableName)
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		layer, err := s.readSeedLayer(dir, opts)
+		if err != nil {
+			return err
+		}
+		if layer == nil {
+			continue
+		}
+		if len(dirs) > 1 {
+			fmt.Fprintf(s.stdout, "Using seed %s (%d tables)\n", dir, len(layer.tables))
+		}
+		layers = append(layers, *layer)
+		for _, table := range layer.tables {
+			if !seen[table] {
+				seen[table] = true
+				tables = append(tables, table)
+			}
 		}
 	}
 
 	if len(tables) == 0 {
-		fmt.Println("No tables found in seed file")
+		fmt.Fprintln(s.stdout, "No tables found in seed file")
 		return nil
 	}
 
@@ -85,28 +178,70 @@ func (s *Seeder) loadSeedData(ctx context.Context, dbURL, seedDir string) error
 
 	// Disable triggers during seed loading to prevent auto-generated records
 	// from conflicting with seed data (e.g., account triggers that create members)
-	if _, err := tx.ExecContext(ctx, "SET session_replication_role = 'replica'"); err != nil {
-		return fmt.Errorf("disabling triggers: %w", err)
+	strategy, err := s.disableTriggers(ctx, tx, opts.Triggers, tables)
+	if err != nil {
+		return err
 	}
+	fmt.Fprintf(s.stdout, "Disabling triggers with %s\n", describeTriggerStrategy(strategy))
 
-	// Truncate all tables in reverse order (respects FK constraints)
-	for i := len(orderedTables) - 1; i >= 0; i-- {
-		table := orderedTables[i]
-		truncateSQL := fmt.Sprintf("TRUNCATE TABLE %s CASCADE", table)
-		if _, err := tx.ExecContext(ctx, truncateSQL); err != nil {
-			return fmt.Errorf("truncating table %s: %w", table, err)
+	// In merge mode, nothing is truncated: every INSERT is upserted on top
+	// of existing data
+	if opts.Mode == ModeMerge {
+		if err := checkMergeable(ctx, tx, tables); err != nil {
+			return err
+		}
+	} else {
+		// Truncate all tables in reverse order (respects FK constraints).
+		// When loading a subset, CASCADE would also empty tables outside
+		// it, so referencing tables must be selected too.
+		truncate := "TRUNCATE TABLE %s CASCADE"
+		if opts.filtered() {
+			truncate = "TRUNCATE TABLE %s"
 		}
+		for i := len(orderedTables) - 1; i >= 0; i-- {
+			table := orderedTables[i]
+			if _, err := tx.ExecContext(ctx, fmt.Sprintf(truncate, table)); err != nil {
+				if opts.filtered() {
+					return fmt.Errorf("truncating table %s (include the tables that reference it, or use --mode=merge): %w", table, err)
+				}
+				return fmt.Errorf("truncating table %s: %w", table, err)
+			}
+		}
+
+	}
+
+	// Execute each seed file statement by statement, base first. Layers
+	// after the first are upserted in either mode, so their rows overwrite
+	// rows of earlier layers with the same primary key.
+	for i, layer := range layers {
+		for _, file := range layer.files {
+			if err := s.loadSeedFile(ctx, tx, file, opts, i > 0); err != nil {
+				return err
+			}
+		}
+	}
+	if opts.Mode == ModeMerge {
+		fmt.Fprintf(s.stdout, "Merged seed data for %d tables\n", len(tables))
+	} else {
+		fmt.Fprintf(s.stdout, "Loaded seed data for %d tables\n", len(tables))
 	}
 
-	// Execute the entire seed file
-	if _, err := tx.ExecContext(ctx, string(content)); err != nil {
-		return fmt.Errorf("executing seed file: %w", err)
+	// Move sequences past the explicit ids in the seed, so the next
+	// generated id doesn't collide with a seeded row
+	adjusted, err := s.resetSequences(ctx, tx, tables)
+	if err != nil {
+		return err
+	}
+	if len(adjusted) > 0 {
+		fmt.Fprintf(s.stdout, "Adjusted %d sequences\n", len(adjusted))
+		for _, seq := range adjusted {
+			fmt.Fprintf(s.stdout, "  %s\n", seq)
+		}
 	}
-	fmt.Printf("Loaded seed data for %d tables\n", len(tables))
 
 	// Re-enable triggers
-	if _, err := tx.ExecContext(ctx, "SET session_replication_role = 'origin'"); err != nil {
-		return fmt.Errorf("re-enabling triggers: %w", err)
+	if err := s.enableTriggers(ctx, tx, strategy, tables); err != nil {
+		return err
 	}
 
 	// Commit transaction
@@ -117,6 +252,129 @@ func (s *Seeder) loadSeedData(ctx context.Context, dbURL, seedDir string) error
 	return nil
 }
 
+// findLoadFile returns the path of the load.sql of dir, with the extension
+// of its compression. Without one, it returns the plain load.sql path.
+func findLoadFile(dir string) (string, error) {
+	var found []string
+	for _, c := range compressions {
+		path := filepath.Join(dir, LoadFile+c.ext())
+		if _, err := os.Stat(path); err == nil {
+			found = append(found, path)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return filepath.Join(dir, LoadFile), nil
+	case 1:
+		return found[0], nil
+	default:
+		return "", fmt.Errorf("found more than one seed file in %s (%s): remove all but one", dir, strings.Join(found, ", "))
+	}
+}
+
+// readSeedLayer finds the seed files of dir and the tables they seed, keeping
+// only the tables selected by opts: the per-table files listed in the
+// manifest, or load.sql (or load.sql.gz, load.sql.zst). It returns nil if dir
+// has no seed data.
+func (s *Seeder) readSeedLayer(dir string, opts ApplyOptions) (*seedLayer, error) {
+	// Per-table files are loaded in the order of the manifest
+	manifest, err := ReadManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	if manifest != nil && manifest.Layout == LayoutTables {
+		layer := &seedLayer{dir: dir}
+		for _, t := range manifest.Tables {
+			if opts.includesTable(t.Name) {
+				layer.files = append(layer.files, filepath.Join(dir, filepath.FromSlash(t.File)))
+				layer.tables = append(layer.tables, t.Name)
+			}
+		}
+		return layer, nil
+	}
+
+	// Look for load.sql, possibly compressed
+	seedFile, err := findLoadFile(dir)
+	if err != nil {
+		return nil, err
+	}
+	f, err := openSeedFile(seedFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			if _, err := os.Stat(filepath.Join(dir, TablesDir)); err == nil {
+				return nil, fmt.Errorf("found %s/ but no %s in %s. Please run 'seed create' to regenerate it", TablesDir, ManifestFile, dir)
+			}
+			// Check for legacy per-table files
+			legacyFiles, _ := filepath.Glob(filepath.Join(dir, "*.*.sql"))
+			if len(legacyFiles) > 0 {
+				return nil, fmt.Errorf("found legacy per-table SQL files but no load.sql in %s. Please run 'seed create' to regenerate seed files in the new format", dir)
+			}
+			fmt.Fprintf(s.stdout, "No load.sql found in seed directory %s\n", dir)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading seed file %s: %w", seedFile, err)
+	}
+
+	defer f.Close()
+
+	// Extract table names from comments (e.g., "-- Table: public.users"),
+	// reading line by line so large files aren't held in memory
+	var tables []string
+	r := bufio.NewReaderSize(f, 64*1024)
+	for {
+		line, err := r.ReadString('\n')
+		if tableName, ok := strings.CutPrefix(line, "-- Table: "); ok {
+			tableName = strings.TrimSpace(tableName)
+			if opts.includesTable(tableName) {
+				tables = append(tables, tableName)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading seed file %s: %w", seedFile, err)
+		}
+	}
+
+	return &seedLayer{dir: dir, files: []string{seedFile}, tables: tables}, nil
+}
+
+// resetSequences sets every serial and identity sequence owned by the given
+// tables to the column's current maximum, so nextval returns max+1. Sequences
+// of empty tables are left alone. It returns a description of each adjusted
+// sequence.
+func (s *Seeder) resetSequences(ctx context.Context, tx *sql.Tx, tables []string) ([]string, error) {
+	var adjusted []string
+	for _, table := range tables {
+		schema, name := splitQualifiedName(table)
+		sequences, err := pgconn.OwnedSequences(ctx, tx, schema, name)
+		if err != nil {
+			return nil, fmt.Errorf("reading sequences of %s: %w", table, err)
+		}
+
+		for _, seq := range sequences {
+			seqName := pgconn.QuoteIdentifier(seq.Schema) + "." + pgconn.QuoteIdentifier(seq.Name)
+			query := fmt.Sprintf("SELECT pg_catalog.setval($1::regclass, MAX(%s)) FROM %s.%s HAVING MAX(%s) IS NOT NULL",
+				pgconn.QuoteIdentifier(seq.Column),
+				pgconn.QuoteIdentifier(schema),
+				pgconn.QuoteIdentifier(name),
+				pgconn.QuoteIdentifier(seq.Column))
+
+			var value int64
+			err := tx.QueryRowContext(ctx, query, seqName).Scan(&value)
+			if err == sql.ErrNoRows {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("resetting sequence %s.%s: %w", seq.Schema, seq.Name, err)
+			}
+			adjusted = append(adjusted, fmt.Sprintf("%s.%s (%s.%s): next value %d", seq.Schema, seq.Name, table, seq.Column, value+1))
+		}
+	}
+	return adjusted, nil
+}
+
 // getImportOrder returns tables sorted by foreign key dependencies
 // Tables with no dependencies come first, tables that depend on others come later
 func (s *Seeder) getImportOrder(ctx context.Context, db *sql.DB, tables []string) ([]string, error) {
@@ -0,0 +1,144 @@
+package seed
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lucasefe/seedup/pkg/pgconn"
+)
+
+// testDB returns the URL in SEEDUP_TEST_DATABASE_URL and a connection to it,
+// skipping the test when it isn't set. Tests create and drop their own
+// schemas in it.
+func testDB(t *testing.T) (string, *sql.DB) {
+	t.Helper()
+	dbURL := os.Getenv("SEEDUP_TEST_DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("SEEDUP_TEST_DATABASE_URL not set")
+	}
+	db, err := pgconn.Open(dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return dbURL, db
+}
+
+func mustExec(t *testing.T, db *sql.DB, query string) {
+	t.Helper()
+	if _, err := db.Exec(query); err != nil {
+		t.Fatalf("%v\n%s", err, query)
+	}
+}
+
+// writeSeed writes a seed set with the given load.sql, extending base
+// unless it is empty
+func writeSeed(t *testing.T, dir, base, load string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, LoadFile), []byte(load), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if base != "" {
+		if err := os.WriteFile(filepath.Join(dir, ExtendsFile), []byte(base+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestApplyOverlayOverwritesBaseRows(t *testing.T) {
+	dbURL, db := testDB(t)
+	drop := "DROP SCHEMA IF EXISTS seedup_layers CASCADE"
+	mustExec(t, db, drop)
+	t.Cleanup(func() { db.Exec(drop) })
+	mustExec(t, db, `
+		CREATE SCHEMA seedup_layers;
+		CREATE TABLE seedup_layers.items (id integer PRIMARY KEY, name text NOT NULL);
+		CREATE TABLE seedup_layers.notes (body text);
+	`)
+
+	root := t.TempDir()
+	writeSeed(t, filepath.Join(root, "dev"), "", `-- Table: seedup_layers.items
+INSERT INTO "seedup_layers"."items" ("id", "name") OVERRIDING SYSTEM VALUE VALUES
+    (1, 'one'),
+    (2, 'two');
+
+-- Table: seedup_layers.notes
+INSERT INTO "seedup_layers"."notes" ("body") OVERRIDING SYSTEM VALUE VALUES
+    ('base');
+`)
+	writeSeed(t, filepath.Join(root, "e2e"), "dev", `-- Table: seedup_layers.items
+INSERT INTO "seedup_layers"."items" ("id", "name") OVERRIDING SYSTEM VALUE VALUES
+    (2, 'overlay two'),
+    (3, 'three');
+
+-- Table: seedup_layers.notes
+INSERT INTO "seedup_layers"."notes" ("body") OVERRIDING SYSTEM VALUE VALUES
+    ('overlay');
+`)
+
+	s := New(WithStdout(io.Discard))
+	opts, err := ApplyOptions{Mode: ModeTruncate}.withDefaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Twice, to check that applying again gives the same rows
+	for range 2 {
+		if err := s.loadSeedData(context.Background(), dbURL, filepath.Join(root, "e2e"), opts); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got, want := queryRows(t, db, "SELECT id || ':' || name FROM seedup_layers.items ORDER BY id"), "1:one,2:overlay two,3:three"; got != want {
+		t.Errorf("items = %q, want %q", got, want)
+	}
+	if got, want := queryRows(t, db, "SELECT body FROM seedup_layers.notes ORDER BY body"), "base,overlay"; got != want {
+		t.Errorf("notes = %q, want %q", got, want)
+	}
+
+	// Merge refuses the table without a primary key
+	opts.Mode = ModeMerge
+	err = s.loadSeedData(context.Background(), dbURL, filepath.Join(root, "e2e"), opts)
+	if err == nil || !strings.Contains(err.Error(), "seedup_layers.notes") {
+		t.Errorf("merge with a table without a primary key: got %v, want an error naming it", err)
+	}
+
+	opts.ExcludeTables = []string{"seedup_layers.notes"}
+	if err := s.loadSeedData(context.Background(), dbURL, filepath.Join(root, "e2e"), opts); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := queryRows(t, db, "SELECT id || ':' || name FROM seedup_layers.items ORDER BY id"), "1:one,2:overlay two,3:three"; got != want {
+		t.Errorf("items after merge = %q, want %q", got, want)
+	}
+}
+
+// queryRows returns the single text column of query's rows, comma-separated
+func queryRows(t *testing.T, db *sql.DB, query string) string {
+	t.Helper()
+	rows, err := db.Query(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, v)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return strings.Join(values, ",")
+}
@@ -0,0 +1,115 @@
+package seed
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression selects how seed files are compressed
+type Compression string
+
+const (
+	// CompressionNone writes plain .sql files. This is the default.
+	CompressionNone Compression = "none"
+	// CompressionGzip writes .sql.gz files
+	CompressionGzip Compression = "gzip"
+	// CompressionZstd writes .sql.zst files
+	CompressionZstd Compression = "zstd"
+)
+
+// compressions lists the supported compressions, uncompressed first
+var compressions = []Compression{CompressionNone, CompressionGzip, CompressionZstd}
+
+// ext returns the file extension added by the compression
+func (c Compression) ext() string {
+	switch c {
+	case CompressionGzip:
+		return ".gz"
+	case CompressionZstd:
+		return ".zst"
+	default:
+		return ""
+	}
+}
+
+// compressionOf returns the compression of a seed file, from its extension
+func compressionOf(path string) Compression {
+	for _, c := range compressions {
+		if c.ext() != "" && strings.HasSuffix(path, c.ext()) {
+			return c
+		}
+	}
+	return CompressionNone
+}
+
+// compressWriter returns a writer that compresses into w. Closing it flushes
+// the compressed stream but doesn't close w.
+func compressWriter(w io.Writer, c Compression) (io.WriteCloser, error) {
+	switch c {
+	case CompressionGzip:
+		return gzip.NewWriter(w), nil
+	case CompressionZstd:
+		enc, err := zstd.NewWriter(w)
+		if err != nil {
+			return nil, fmt.Errorf("creating zstd encoder: %w", err)
+		}
+		return enc, nil
+	default:
+		return nopWriteCloser{w}, nil
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// openSeedFile opens a seed file, decompressing it while it is read if its
+// extension says it is compressed
+func openSeedFile(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var r io.ReadCloser
+	switch compressionOf(path) {
+	case CompressionGzip:
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+		r = gz
+	case CompressionZstd:
+		dec, err := zstd.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+		r = dec.IOReadCloser()
+	default:
+		return f, nil
+	}
+	return &seedFileReader{ReadCloser: r, file: f}, nil
+}
+
+// seedFileReader closes both the decompressor and the file under it
+type seedFileReader struct {
+	io.ReadCloser
+	file *os.File
+}
+
+func (r *seedFileReader) Close() error {
+	err := r.ReadCloser.Close()
+	if closeErr := r.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,106 @@
+package seed
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompressRoundTrip(t *testing.T) {
+	content := strings.Repeat("INSERT INTO \"public\".\"users\" (\"id\") VALUES (1);\n", 1000)
+
+	tests := []struct {
+		compression Compression
+		magic       []byte
+	}{
+		{CompressionNone, []byte("INSERT")},
+		{CompressionGzip, []byte{0x1f, 0x8b}},
+		{CompressionZstd, []byte{0x28, 0xb5, 0x2f, 0xfd}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.compression), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), LoadFile+tt.compression.ext())
+			f, err := os.Create(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w, err := compressWriter(f, tt.compression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := io.WriteString(w, content); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if err := f.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.HasPrefix(raw, tt.magic) {
+				t.Errorf("%s starts with % x, want % x", filepath.Base(path), raw[:4], tt.magic)
+			}
+			if tt.compression != CompressionNone && len(raw) >= len(content) {
+				t.Errorf("%s is %d bytes, not smaller than the %d bytes written", filepath.Base(path), len(raw), len(content))
+			}
+
+			r, err := openSeedFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := r.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != content {
+				t.Errorf("read back %d bytes, want the %d bytes written", len(got), len(content))
+			}
+		})
+	}
+}
+
+func TestOpenSeedFileCorrupt(t *testing.T) {
+	for _, name := range []string{"load.sql.gz", "load.sql.zst"} {
+		path := filepath.Join(t.TempDir(), name)
+		if err := os.WriteFile(path, []byte("not compressed"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		r, err := openSeedFile(path)
+		if err == nil {
+			_, err = io.ReadAll(r)
+			r.Close()
+		}
+		if err == nil {
+			t.Errorf("reading corrupt %s: got no error", name)
+		}
+	}
+}
+
+func TestCompressionOf(t *testing.T) {
+	tests := []struct {
+		path string
+		want Compression
+	}{
+		{"seed/dev/load.sql", CompressionNone},
+		{"seed/dev/load.sql.gz", CompressionGzip},
+		{"seed/dev/tables/public.users.sql.zst", CompressionZstd},
+		{"seed/dev/load.sql.bak", CompressionNone},
+	}
+	for _, tt := range tests {
+		if got := compressionOf(tt.path); got != tt.want {
+			t.Errorf("compressionOf(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
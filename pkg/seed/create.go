@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
@@ -21,11 +22,29 @@ type CreateOptions struct {
 	Schemas []string
 	// AllSchemas includes all non-system schemas when true.
 	AllSchemas bool
+	// BatchSize is the number of rows per INSERT statement.
+	// Defaults to DefaultBatchSize.
+	BatchSize int
+	// Layout selects how the seed is stored. Defaults to the layout of the
+	// existing seed, or LayoutSingle.
+	Layout Layout
+	// Compression selects how seed files are compressed. Defaults to the
+	// compression of the existing seed, or CompressionNone.
+	Compression Compression
 }
 
+// DefaultBatchSize is the number of rows per INSERT statement in load.sql
+const DefaultBatchSize = 500
+
 // Create creates seed data from a database
-// It exports seed data to a single load.sql file
+// It exports seed data to load.sql, or to one file per table with
+// LayoutTables, optionally compressed, and lists the tables in manifest.json
 func (s *Seeder) Create(ctx context.Context, dbURL, seedDir, queryFile string, opts CreateOptions) error {
+	layout, compression, err := resolveFormat(seedDir, opts.Layout, opts.Compression)
+	if err != nil {
+		return err
+	}
+
 	// Ensure seed directory exists
 	if err := os.MkdirAll(seedDir, 0755); err != nil {
 		return fmt.Errorf("creating seed directory: %w", err)
@@ -75,20 +94,29 @@ func (s *Seeder) Create(ctx context.Context, dbURL, seedDir, queryFile string, o
 		}
 	}
 
-	// Extract seed data to a single output file
-	outputFile := filepath.Join(seedDir, "load.sql")
-	if err := s.extractSeedData(ctx, db, orderedTables, queryFile, outputFile); err != nil {
+	// Extract seed data to the seed files
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	w, err := newSeedWriter(seedDir, layout, compression, opts.DryRun)
+	if err != nil {
+		return err
+	}
+	if err := s.extractSeedData(ctx, db, orderedTables, queryFile, w, opts.BatchSize); err != nil {
+		w.abort()
 		return fmt.Errorf("extracting seed data: %w", err)
 	}
+	if err := w.close(); err != nil {
+		return err
+	}
 
 	if opts.DryRun {
 		fmt.Println("Dry run mode - not modifying any files")
-		// Remove the generated file in dry run mode
-		os.Remove(outputFile)
 		return nil
 	}
 
-	// Clean old per-table seed files (legacy format)
+	// Clean old per-table seed files (legacy format). Per-table files of
+	// LayoutTables live in tables/ and are cleaned up by the seed writer.
 	oldCSVs, _ := filepath.Glob(filepath.Join(seedDir, "*.csv"))
 	for _, csv := range oldCSVs {
 		os.Remove(csv)
@@ -102,6 +130,43 @@ func (s *Seeder) Create(ctx context.Context, dbURL, seedDir, queryFile string, o
 	return nil
 }
 
+// resolveFormat validates layout and compression. Each defaults to the one
+// recorded in the manifest of seedDir, or LayoutSingle and CompressionNone.
+func resolveFormat(seedDir string, layout Layout, compression Compression) (Layout, Compression, error) {
+	switch layout {
+	case "", LayoutSingle, LayoutTables:
+	default:
+		return "", "", fmt.Errorf("invalid layout %q (want %s or %s)", layout, LayoutSingle, LayoutTables)
+	}
+	switch compression {
+	case "", CompressionNone, CompressionGzip, CompressionZstd:
+	default:
+		return "", "", fmt.Errorf("invalid compression %q (want %s, %s or %s)",
+			compression, CompressionNone, CompressionGzip, CompressionZstd)
+	}
+	if layout != "" && compression != "" {
+		return layout, compression, nil
+	}
+
+	m, err := ReadManifest(seedDir)
+	if err != nil {
+		return "", "", err
+	}
+	if layout == "" {
+		layout = LayoutSingle
+		if m != nil && m.Layout == LayoutTables {
+			layout = LayoutTables
+		}
+	}
+	if compression == "" {
+		compression = CompressionNone
+		if m != nil && m.Compression != "" {
+			compression = m.Compression
+		}
+	}
+	return layout, compression, nil
+}
+
 type tableInfo struct {
 	Schema string
 	Name   string
@@ -155,7 +220,7 @@ func (s *Seeder) getTables(ctx context.Context, db *sql.DB, opts CreateOptions)
 	return tables, nil
 }
 
-func (s *Seeder) extractSeedData(ctx context.Context, db *sql.DB, tables []tableInfo, queryFile, outputFile string) error {
+func (s *Seeder) extractSeedData(ctx context.Context, db *sql.DB, tables []tableInfo, queryFile string, w *seedWriter, batchSize int) error {
 	// Start a transaction for temp table visibility
 	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
@@ -163,6 +228,19 @@ func (s *Seeder) extractSeedData(ctx context.Context, db *sql.DB, tables []table
 	}
 	defer tx.Rollback()
 
+	// Fix the output format of dates, intervals and floats, so the seed
+	// doesn't change with the server's or the user's settings
+	for _, setting := range []string{
+		"SET LOCAL TimeZone = 'UTC'",
+		"SET LOCAL DateStyle = 'ISO, YMD'",
+		"SET LOCAL IntervalStyle = 'postgres'",
+		"SET LOCAL extra_float_digits = 1",
+	} {
+		if _, err := tx.ExecContext(ctx, setting); err != nil {
+			return fmt.Errorf("configuring session (%s): %w", setting, err)
+		}
+	}
+
 	// Step 3: Prepare temporary tables
 	fmt.Println("[3/5] Preparing temporary tables...")
 	for _, t := range tables {
@@ -200,78 +278,64 @@ func (s *Seeder) extractSeedData(ctx context.Context, db *sql.DB, tables []table
 	// Step 5: Export seed data
 	fmt.Println("[5/5] Exporting seed data...")
 
-	// Build output file content with all tables
-	var output strings.Builder
-	output.WriteString("-- Seed data generated by seedup\n")
-	output.WriteString("-- Tables are ordered by foreign key dependencies\n\n")
-
-	// Generate INSERT statements for each table
+	// Stream INSERT statements for each table to the seed files
 	totalRows := 0
 	for _, t := range tables {
-		insertSQL, rowCount, err := s.generateTableInserts(ctx, tx, t)
+		rowCount, err := w.writeTable(t, func(out io.Writer) (int, error) {
+			return s.generateTableInserts(ctx, tx, t, batchSize, out)
+		})
 		if err != nil {
 			return fmt.Errorf("generating inserts for %s.%s: %w", t.Schema, t.Name, err)
 		}
-		if insertSQL != "" {
-			output.WriteString(insertSQL)
-			output.WriteString("\n")
-		}
 		totalRows += rowCount
 		fmt.Printf("      %s.%s (%d rows)\n", t.Schema, t.Name, rowCount)
 	}
 
-	// Write the consolidated output file
-	if err := os.WriteFile(outputFile, []byte(output.String()), 0644); err != nil {
-		return fmt.Errorf("writing output file: %w", err)
+	if w.layout == LayoutTables {
+		fmt.Printf("      Wrote %d table files to %s/ (%d rows total)\n", len(w.manifest.Tables), TablesDir, totalRows)
+	} else {
+		fmt.Printf("      Wrote %s (%d rows total)\n", w.loadFile(), totalRows)
 	}
 
-	fmt.Printf("      Wrote load.sql (%d rows total)\n", totalRows)
-
 	// No need to commit - the deferred tx.Rollback() will clean up temp tables
 	return nil
 }
 
-// generateTableInserts generates a batched INSERT statement for a table.
-// Returns the SQL string and row count. Returns empty string if no rows.
-func (s *Seeder) generateTableInserts(ctx context.Context, tx *sql.Tx, t tableInfo) (string, int, error) {
+// generateTableInserts writes batched INSERT statements for a table to w, with
+// batchSize rows per statement. Rows are ordered by the table's primary key,
+// or by every column when it has none, so the output only changes where the
+// data does. Rows are streamed, one batch at a time. Returns the row count;
+// nothing is written if there are no rows.
+func (s *Seeder) generateTableInserts(ctx context.Context, tx *sql.Tx, t tableInfo, batchSize int, w io.Writer) (int, error) {
 	tempTableName := fmt.Sprintf(`pg_temp.seed.%s.%s`, t.Schema, t.Name)
 	tempTableQuoted := fmt.Sprintf(`"seed.%s.%s"`, t.Schema, t.Name)
 
 	// Get column info for the temp table
 	allColumns, err := pgconn.GetColumnInfo(ctx, tx, tempTableName)
 	if err != nil {
-		return "", 0, fmt.Errorf("getting column info: %w", err)
+		return 0, fmt.Errorf("getting column info: %w", err)
 	}
 
 	if len(allColumns) == 0 {
 		// No columns found, skip this table
 		fmt.Printf("      Warning: no columns found for table %s.%s\n", t.Schema, t.Name)
-		return "", 0, nil
+		return 0, nil
 	}
 
 	// Filter out generated columns (cannot INSERT into them)
 	var insertableColumns []pgconn.ColumnInfo
-	var insertableIndices []int
-	for i, col := range allColumns {
+	for _, col := range allColumns {
 		if !col.IsGenerated {
 			insertableColumns = append(insertableColumns, col)
-			insertableIndices = append(insertableIndices, i)
 		}
 	}
 
 	if len(insertableColumns) == 0 {
 		// All columns are generated, skip this table
 		fmt.Printf("      Warning: all columns are generated for table %s.%s\n", t.Schema, t.Name)
-		return "", 0, nil
+		return 0, nil
 	}
 
-	// Query all rows from the temp table
-	rows, err := tx.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s", tempTableQuoted))
-	if err != nil {
-		return "", 0, fmt.Errorf("querying temp table: %w", err)
-	}
-	defer rows.Close()
-
 	// Build column names list for INSERT statements (only insertable columns)
 	colNames := make([]string, len(insertableColumns))
 	for i, col := range insertableColumns {
@@ -279,52 +343,73 @@ func (s *Seeder) generateTableInserts(ctx context.Context, tx *sql.Tx, t tableIn
 	}
 	colNamesStr := strings.Join(colNames, ", ")
 
-	// Collect all row values
-	var valueRows []string
+	// Order by the primary key of the real table, not the temp table
+	orderBy, err := pgconn.OrderByColumns(ctx, tx, t.Schema, t.Name, colNames)
+	if err != nil {
+		return 0, err
+	}
+
+	// Query all rows from the temp table
+	query := fmt.Sprintf("SELECT %s FROM %s ORDER BY %s", colNamesStr, tempTableQuoted, orderBy)
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("querying temp table: %w", err)
+	}
+	defer rows.Close()
+
+	// Write one INSERT statement per batch of rows. OVERRIDING SYSTEM VALUE
+	// lets the rows keep their ids in GENERATED ALWAYS identity columns.
+	insert := fmt.Sprintf("INSERT INTO %s.%s (%s) OVERRIDING SYSTEM VALUE VALUES\n",
+		pgconn.QuoteIdentifier(t.Schema),
+		pgconn.QuoteIdentifier(t.Name),
+		colNamesStr,
+	)
+	batch := make([]string, 0, batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		_, err := io.WriteString(w, insert+strings.Join(batch, ",\n")+";\n")
+		batch = batch[:0]
+		return err
+	}
+
+	rowCount := 0
 	for rows.Next() {
-		// Create scan destinations for ALL columns (SELECT * returns all)
-		allValues := make([]any, len(allColumns))
-		valuePtrs := make([]any, len(allColumns))
-		for i := range allValues {
-			valuePtrs[i] = &allValues[i]
+		values := make([]any, len(insertableColumns))
+		valuePtrs := make([]any, len(insertableColumns))
+		for i := range values {
+			valuePtrs[i] = &values[i]
 		}
 
 		if err := rows.Scan(valuePtrs...); err != nil {
-			return "", 0, fmt.Errorf("scanning row: %w", err)
+			return 0, fmt.Errorf("scanning row: %w", err)
 		}
 
-		// Extract only insertable column values
-		insertableValues := make([]any, len(insertableIndices))
-		for i, idx := range insertableIndices {
-			insertableValues[i] = allValues[idx]
+		if rowCount == 0 {
+			if _, err := fmt.Fprintf(w, "-- Table: %s.%s\n", t.Schema, t.Name); err != nil {
+				return 0, fmt.Errorf("writing inserts: %w", err)
+			}
 		}
+		rowCount++
 
-		// Serialize values (only insertable columns)
-		serialized := pgconn.SerializeRow(insertableValues, insertableColumns)
-		valueRows = append(valueRows, fmt.Sprintf("    (%s)", strings.Join(serialized, ", ")))
+		serialized := pgconn.SerializeRow(values, insertableColumns)
+		batch = append(batch, fmt.Sprintf("    (%s)", strings.Join(serialized, ", ")))
+		if len(batch) == batchSize {
+			if err := flush(); err != nil {
+				return 0, fmt.Errorf("writing inserts: %w", err)
+			}
+		}
 	}
 
 	if err := rows.Err(); err != nil {
-		return "", 0, fmt.Errorf("iterating rows: %w", err)
+		return 0, fmt.Errorf("iterating rows: %w", err)
 	}
-
-	// Return empty string if no data
-	if len(valueRows) == 0 {
-		return "", 0, nil
+	if err := flush(); err != nil {
+		return 0, fmt.Errorf("writing inserts: %w", err)
 	}
 
-	// Build batched INSERT statement
-	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("-- Table: %s.%s\n", t.Schema, t.Name))
-	sb.WriteString(fmt.Sprintf("INSERT INTO %s.%s (%s) VALUES\n",
-		pgconn.QuoteIdentifier(t.Schema),
-		pgconn.QuoteIdentifier(t.Name),
-		colNamesStr,
-	))
-	sb.WriteString(strings.Join(valueRows, ",\n"))
-	sb.WriteString(";\n")
-
-	return sb.String(), len(valueRows), nil
+	return rowCount, nil
 }
 
 // getTableOrder returns tables sorted by foreign key dependencies.
@@ -0,0 +1,75 @@
+package seed
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lucasefe/seedup/pkg/pgconn"
+)
+
+// ExtendsFile is the file in a seed directory that names the seed set it
+// extends, e.g. seed/e2e/extends containing "dev". The base is loaded before
+// the seed set itself.
+const ExtendsFile = "extends"
+
+// seedLayer is the seed data of one seed set in an extends chain
+type seedLayer struct {
+	dir string
+	// files holds the layer's statements, in load order: load.sql, or the
+	// per-table files listed in the manifest
+	files  []string
+	tables []string
+}
+
+// resolveSeedLayers returns seedDir and the seed sets it extends, base first.
+// A relative base name is resolved next to seedDir.
+func resolveSeedLayers(seedDir string) ([]string, error) {
+	var chain []string
+	visited := make(map[string]bool)
+
+	for dir := filepath.Clean(seedDir); dir != ""; {
+		if visited[dir] {
+			return nil, fmt.Errorf("seed %s has an extends cycle (%s)", seedDir, strings.Join(append(chain, dir), " -> "))
+		}
+		visited[dir] = true
+		chain = append(chain, dir)
+
+		data, err := os.ReadFile(filepath.Join(dir, ExtendsFile))
+		if os.IsNotExist(err) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", filepath.Join(dir, ExtendsFile), err)
+		}
+
+		base := strings.TrimSpace(string(data))
+		switch {
+		case base == "":
+			dir = ""
+		case filepath.IsAbs(base):
+			dir = filepath.Clean(base)
+		default:
+			dir = filepath.Join(filepath.Dir(dir), base)
+		}
+	}
+
+	// Base first
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain, nil
+}
+
+// includesTable reports whether the table named by a "-- Table:" header is
+// selected by the Tables and ExcludeTables options
+func (o ApplyOptions) includesTable(table string) bool {
+	schema, name := splitQualifiedName(table)
+	return pgconn.MatchTable(o.Tables, o.ExcludeTables, schema, name)
+}
+
+// filtered reports whether the options select a subset of the seed's tables
+func (o ApplyOptions) filtered() bool {
+	return len(o.Tables) > 0 || len(o.ExcludeTables) > 0
+}
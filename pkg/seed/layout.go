@@ -0,0 +1,306 @@
+package seed
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Layout selects how seed data is stored in a seed directory
+type Layout string
+
+const (
+	// LayoutSingle stores every table in load.sql. This is the default.
+	LayoutSingle Layout = "single"
+	// LayoutTables stores each table in tables/<schema>.<table>.sql, which
+	// keeps diffs and merge conflicts to the tables that changed
+	LayoutTables Layout = "tables"
+)
+
+const (
+	// LoadFile is the seed file of LayoutSingle
+	LoadFile = "load.sql"
+	// TablesDir is the directory of per-table files in LayoutTables
+	TablesDir = "tables"
+	// ManifestFile lists the seeded tables in load order
+	ManifestFile = "manifest.json"
+)
+
+// Manifest describes the tables of a seed directory, written by Create
+type Manifest struct {
+	Layout      Layout          `json:"layout"`
+	Compression Compression     `json:"compression,omitempty"`
+	Tables      []ManifestTable `json:"tables"`
+}
+
+// ManifestTable is one seeded table, in load order
+type ManifestTable struct {
+	// Name is the schema-qualified table name
+	Name string `json:"name"`
+	// File holds the table's statements, relative to the seed directory
+	File string `json:"file"`
+	Rows int    `json:"rows"`
+	// SHA256 is the checksum of the table's statements, before compression,
+	// so changing the compression doesn't change it
+	SHA256 string `json:"sha256"`
+}
+
+// ReadManifest reads the manifest of seedDir. It returns nil if there is
+// none, as in seeds created before manifests were written.
+func ReadManifest(seedDir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(seedDir, ManifestFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading manifest: %w", err)
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", filepath.Join(seedDir, ManifestFile), err)
+	}
+	return &m, nil
+}
+
+// tableFile returns the path of a table's file in LayoutTables, relative to
+// the seed directory
+func tableFile(t tableInfo, c Compression) string {
+	return filepath.Join(TablesDir, t.Schema+"."+t.Name+".sql"+c.ext())
+}
+
+// seedWriter writes the tables of a seed in one of the layouts, and the
+// manifest when closed. Files are written next to their final path with a
+// .tmp suffix and only renamed into place by close, so a failed run leaves
+// the previous seed intact. With dryRun it only computes what it would write.
+type seedWriter struct {
+	dir         string
+	layout      Layout
+	compression Compression
+	dryRun      bool
+	load        *compressedFile
+	pending     []string // files to rename into place, relative to dir
+	manifest    Manifest
+}
+
+// compressedFile is a seed file being written through its compressor
+type compressedFile struct {
+	io.WriteCloser
+	file *os.File
+	name string
+}
+
+// Close flushes the compressor and closes the file
+func (f *compressedFile) Close() error {
+	err := f.WriteCloser.Close()
+	if closeErr := f.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("writing %s: %w", f.name, err)
+	}
+	return nil
+}
+
+// loadHeader starts load.sql
+const loadHeader = "-- Seed data generated by seedup\n-- Tables are ordered by foreign key dependencies\n\n"
+
+func newSeedWriter(dir string, layout Layout, compression Compression, dryRun bool) (*seedWriter, error) {
+	w := &seedWriter{
+		dir:         dir,
+		layout:      layout,
+		compression: compression,
+		dryRun:      dryRun,
+		manifest:    Manifest{Layout: layout, Compression: compression},
+	}
+	if dryRun {
+		return w, nil
+	}
+
+	if layout == LayoutTables {
+		if err := os.MkdirAll(filepath.Join(dir, TablesDir), 0755); err != nil {
+			return nil, fmt.Errorf("creating tables directory: %w", err)
+		}
+		return w, nil
+	}
+
+	f, err := w.create(w.loadFile())
+	if err != nil {
+		return nil, err
+	}
+	w.load = f
+	if _, err := io.WriteString(f, loadHeader); err != nil {
+		w.abort()
+		return nil, fmt.Errorf("writing %s: %w", f.name, err)
+	}
+	return w, nil
+}
+
+// loadFile returns the name of load.sql with the compression's extension
+func (w *seedWriter) loadFile() string {
+	return LoadFile + w.compression.ext()
+}
+
+// create opens the temporary file for file, relative to the seed directory,
+// with its compressor
+func (w *seedWriter) create(file string) (*compressedFile, error) {
+	f, err := os.Create(filepath.Join(w.dir, file+".tmp"))
+	if err != nil {
+		return nil, fmt.Errorf("creating %s: %w", file, err)
+	}
+	w.pending = append(w.pending, file)
+
+	cw, err := compressWriter(f, w.compression)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &compressedFile{WriteCloser: cw, file: f, name: file}, nil
+}
+
+// writeTable writes a table's statements, produced by generate, and records
+// them in the manifest. The checksum covers the statements before
+// compression. Tables without rows are left out.
+func (w *seedWriter) writeTable(t tableInfo, generate func(io.Writer) (int, error)) (int, error) {
+	file := w.loadFile()
+	if w.layout == LayoutTables {
+		file = tableFile(t, w.compression)
+	}
+
+	var out io.Writer = io.Discard
+	var tableOut *compressedFile
+	if !w.dryRun {
+		if w.layout == LayoutTables {
+			f, err := w.create(file)
+			if err != nil {
+				return 0, err
+			}
+			tableOut = f
+			out = f
+		} else {
+			out = w.load
+		}
+	}
+
+	sum := sha256.New()
+	rows, err := generate(io.MultiWriter(out, sum))
+	if tableOut != nil {
+		if closeErr := tableOut.Close(); err == nil && closeErr != nil {
+			err = closeErr
+		}
+		if rows == 0 {
+			// Nothing to keep: drop the empty file
+			os.Remove(tableOut.file.Name())
+			w.pending = w.pending[:len(w.pending)-1]
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	if rows == 0 {
+		return 0, nil
+	}
+
+	if w.load != nil {
+		if _, err := io.WriteString(w.load, "\n"); err != nil {
+			return 0, fmt.Errorf("writing %s: %w", file, err)
+		}
+	}
+
+	w.manifest.Tables = append(w.manifest.Tables, ManifestTable{
+		Name:   t.Schema + "." + t.Name,
+		File:   filepath.ToSlash(file),
+		Rows:   rows,
+		SHA256: checksum(sum),
+	})
+	return rows, nil
+}
+
+// close moves the new seed files into place, writes the manifest and
+// removes files the new seed doesn't use: per-table files of tables that are
+// gone, and the files of the other layout or compressions.
+func (w *seedWriter) close() error {
+	if w.dryRun {
+		return nil
+	}
+
+	if w.load != nil {
+		err := w.load.Close()
+		w.load = nil
+		if err != nil {
+			w.abort()
+			return err
+		}
+	}
+
+	for _, file := range w.pending {
+		path := filepath.Join(w.dir, file)
+		if err := os.Rename(path+".tmp", path); err != nil {
+			return fmt.Errorf("moving %s into place: %w", file, err)
+		}
+	}
+	w.pending = nil
+
+	data, err := json.MarshalIndent(w.manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(w.dir, ManifestFile), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing manifest: %w", err)
+	}
+
+	written := make(map[string]bool)
+	for _, t := range w.manifest.Tables {
+		written[filepath.FromSlash(t.File)] = true
+	}
+
+	for _, c := range compressions {
+		file := LoadFile + c.ext()
+		if w.layout == LayoutTables || file != w.loadFile() {
+			if err := os.Remove(filepath.Join(w.dir, file)); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("removing %s: %w", file, err)
+			}
+		}
+	}
+	var tableFiles []string
+	for _, c := range compressions {
+		matches, _ := filepath.Glob(filepath.Join(w.dir, TablesDir, "*.sql"+c.ext()))
+		tableFiles = append(tableFiles, matches...)
+	}
+	for _, path := range tableFiles {
+		rel, err := filepath.Rel(w.dir, path)
+		if err != nil || written[rel] {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("removing stale %s: %w", rel, err)
+		}
+	}
+	if w.layout != LayoutTables {
+		// Only succeeds if the directory is empty
+		os.Remove(filepath.Join(w.dir, TablesDir))
+	}
+
+	return nil
+}
+
+// abort removes the temporary files after a failure
+func (w *seedWriter) abort() {
+	if w.load != nil {
+		w.load.Close()
+		w.load = nil
+	}
+	for _, file := range w.pending {
+		os.Remove(filepath.Join(w.dir, file+".tmp"))
+	}
+	w.pending = nil
+}
+
+func checksum(h hash.Hash) string {
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,318 @@
+package seed
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// seedTable is a table written by writeLayout, with its statements and rows
+type seedTable struct {
+	info tableInfo
+	sql  string
+	rows int
+}
+
+var testTables = []seedTable{
+	{tableInfo{"public", "users"}, "INSERT INTO \"public\".\"users\" (\"id\") VALUES\n    (1),\n    (2);\n", 2},
+	{tableInfo{"public", "empty"}, "", 0},
+	{tableInfo{"app", "posts"}, "INSERT INTO \"app\".\"posts\" (\"id\") VALUES\n    (1);\n", 1},
+}
+
+// writeLayout writes tables to dir the way Create does
+func writeLayout(t *testing.T, dir string, layout Layout, compression Compression, tables []seedTable) {
+	t.Helper()
+	w, err := newSeedWriter(dir, layout, compression, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tbl := range tables {
+		_, err := w.writeTable(tbl.info, func(out io.Writer) (int, error) {
+			_, err := io.WriteString(out, tbl.sql)
+			return tbl.rows, err
+		})
+		if err != nil {
+			w.abort()
+			t.Fatal(err)
+		}
+	}
+	if err := w.close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readSeedFile(t *testing.T, path string) string {
+	t.Helper()
+	r, err := openSeedFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// seedFiles lists the files of dir, relative to it
+func seedFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	var files []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestSeedWriterManifest(t *testing.T) {
+	tests := []struct {
+		layout      Layout
+		compression Compression
+		files       []string
+	}{
+		{LayoutSingle, CompressionNone, []string{"load.sql", "load.sql"}},
+		{LayoutSingle, CompressionZstd, []string{"load.sql.zst", "load.sql.zst"}},
+		{LayoutTables, CompressionNone, []string{"tables/public.users.sql", "tables/app.posts.sql"}},
+		{LayoutTables, CompressionGzip, []string{"tables/public.users.sql.gz", "tables/app.posts.sql.gz"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.layout)+"/"+string(tt.compression), func(t *testing.T) {
+			dir := t.TempDir()
+			writeLayout(t, dir, tt.layout, tt.compression, testTables)
+
+			m, err := ReadManifest(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := &Manifest{
+				Layout:      tt.layout,
+				Compression: tt.compression,
+				Tables: []ManifestTable{
+					{Name: "public.users", File: tt.files[0], Rows: 2, SHA256: sha256Hex(testTables[0].sql)},
+					{Name: "app.posts", File: tt.files[1], Rows: 1, SHA256: sha256Hex(testTables[2].sql)},
+				},
+			}
+			if !reflect.DeepEqual(m, want) {
+				t.Errorf("manifest = %+v, want %+v", m, want)
+			}
+
+			if tt.layout == LayoutTables {
+				for i, tbl := range []seedTable{testTables[0], testTables[2]} {
+					if got := readSeedFile(t, filepath.Join(dir, tt.files[i])); got != tbl.sql {
+						t.Errorf("%s = %q, want %q", tt.files[i], got, tbl.sql)
+					}
+				}
+				if _, err := os.Stat(filepath.Join(dir, tableFile(testTables[1].info, tt.compression))); !os.IsNotExist(err) {
+					t.Errorf("table without rows has a file (stat error %v)", err)
+				}
+			} else {
+				wantLoad := loadHeader + testTables[0].sql + "\n" + testTables[2].sql + "\n"
+				if got := readSeedFile(t, filepath.Join(dir, tt.files[0])); got != wantLoad {
+					t.Errorf("%s = %q, want %q", tt.files[0], got, wantLoad)
+				}
+			}
+
+			for _, file := range seedFiles(t, dir) {
+				if strings.HasSuffix(file, ".tmp") {
+					t.Errorf("temporary file %s left behind", file)
+				}
+			}
+		})
+	}
+}
+
+func TestReadManifestMissing(t *testing.T) {
+	m, err := ReadManifest(t.TempDir())
+	if err != nil || m != nil {
+		t.Errorf("ReadManifest of a directory without manifest = %v, %v, want nil, nil", m, err)
+	}
+}
+
+func TestSeedWriterChecksumIgnoresCompression(t *testing.T) {
+	var sums []string
+	for _, c := range compressions {
+		dir := t.TempDir()
+		writeLayout(t, dir, LayoutTables, c, testTables[:1])
+		m, err := ReadManifest(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sums = append(sums, m.Tables[0].SHA256)
+	}
+	for i, sum := range sums {
+		if sum != sha256Hex(testTables[0].sql) {
+			t.Errorf("%s checksum = %s, want the checksum of the uncompressed statements", compressions[i], sum)
+		}
+	}
+}
+
+func TestSeedWriterRemovesUnusedFiles(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []Layout
+		comps []Compression
+		files []string
+	}{
+		{
+			name:  "single to tables",
+			steps: []Layout{LayoutSingle, LayoutTables},
+			comps: []Compression{CompressionNone, CompressionNone},
+			files: []string{"manifest.json", "tables/app.posts.sql", "tables/public.users.sql"},
+		},
+		{
+			name:  "tables to single",
+			steps: []Layout{LayoutTables, LayoutSingle},
+			comps: []Compression{CompressionGzip, CompressionNone},
+			files: []string{"load.sql", "manifest.json"},
+		},
+		{
+			name:  "other compression",
+			steps: []Layout{LayoutSingle, LayoutSingle},
+			comps: []Compression{CompressionNone, CompressionZstd},
+			files: []string{"load.sql.zst", "manifest.json"},
+		},
+		{
+			name:  "other table compression",
+			steps: []Layout{LayoutTables, LayoutTables},
+			comps: []Compression{CompressionZstd, CompressionGzip},
+			files: []string{"manifest.json", "tables/app.posts.sql.gz", "tables/public.users.sql.gz"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for i := range tt.steps {
+				writeLayout(t, dir, tt.steps[i], tt.comps[i], testTables)
+			}
+			if got := seedFiles(t, dir); !reflect.DeepEqual(got, tt.files) {
+				t.Errorf("files = %v, want %v", got, tt.files)
+			}
+			if _, err := os.Stat(filepath.Join(dir, TablesDir)); tt.steps[len(tt.steps)-1] == LayoutSingle && !os.IsNotExist(err) {
+				t.Errorf("%s directory left behind (stat error %v)", TablesDir, err)
+			}
+		})
+	}
+}
+
+func TestSeedWriterRemovesStaleTables(t *testing.T) {
+	dir := t.TempDir()
+	writeLayout(t, dir, LayoutTables, CompressionNone, testTables)
+
+	// A file seedup didn't write is left alone
+	notes := filepath.Join(dir, TablesDir, "README.md")
+	if err := os.WriteFile(notes, []byte("notes\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	writeLayout(t, dir, LayoutTables, CompressionNone, testTables[:1])
+
+	want := []string{"manifest.json", "tables/README.md", "tables/public.users.sql"}
+	if got := seedFiles(t, dir); !reflect.DeepEqual(got, want) {
+		t.Errorf("files = %v, want %v", got, want)
+	}
+}
+
+func TestSeedWriterDryRun(t *testing.T) {
+	dir := t.TempDir()
+	w, err := newSeedWriter(dir, LayoutTables, CompressionGzip, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := w.writeTable(testTables[0].info, func(out io.Writer) (int, error) {
+		_, err := io.WriteString(out, testTables[0].sql)
+		return testTables[0].rows, err
+	})
+	if err != nil || rows != testTables[0].rows {
+		t.Fatalf("writeTable = %d, %v, want %d, nil", rows, err, testTables[0].rows)
+	}
+	if err := w.close(); err != nil {
+		t.Fatal(err)
+	}
+	if files := seedFiles(t, dir); len(files) != 0 {
+		t.Errorf("dry run wrote %v", files)
+	}
+}
+
+func TestResolveFormat(t *testing.T) {
+	tests := []struct {
+		name        string
+		existing    *Manifest
+		layout      Layout
+		compression Compression
+		wantLayout  Layout
+		wantComp    Compression
+		wantErr     bool
+	}{
+		{name: "defaults", wantLayout: LayoutSingle, wantComp: CompressionNone},
+		{
+			name:       "keeps existing",
+			existing:   &Manifest{Layout: LayoutTables, Compression: CompressionZstd},
+			wantLayout: LayoutTables, wantComp: CompressionZstd,
+		},
+		{
+			name:       "keeps existing compression",
+			existing:   &Manifest{Layout: LayoutTables, Compression: CompressionGzip},
+			layout:     LayoutSingle,
+			wantLayout: LayoutSingle, wantComp: CompressionGzip,
+		},
+		{
+			name:        "keeps existing layout",
+			existing:    &Manifest{Layout: LayoutTables},
+			compression: CompressionZstd,
+			wantLayout:  LayoutTables, wantComp: CompressionZstd,
+		},
+		{
+			name:        "explicit",
+			existing:    &Manifest{Layout: LayoutTables, Compression: CompressionZstd},
+			layout:      LayoutSingle,
+			compression: CompressionNone,
+			wantLayout:  LayoutSingle, wantComp: CompressionNone,
+		},
+		{name: "invalid layout", layout: "split", wantErr: true},
+		{name: "invalid compression", compression: "bzip2", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.existing != nil {
+				writeLayout(t, dir, tt.existing.Layout, tt.existing.Compression, testTables)
+			}
+			layout, compression, err := resolveFormat(dir, tt.layout, tt.compression)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("resolveFormat(%q, %q) = %q, %q, want an error", tt.layout, tt.compression, layout, compression)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if layout != tt.wantLayout || compression != tt.wantComp {
+				t.Errorf("resolveFormat(%q, %q) = %q, %q, want %q, %q",
+					tt.layout, tt.compression, layout, compression, tt.wantLayout, tt.wantComp)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package seed
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// loadSeedFile streams the statements of a seed file into tx one at a time,
+// decompressing it on the fly if needed, and skipping tables that opts
+// doesn't select. It prints the rows loaded for each table as it finishes.
+//
+// Files of an overlay (a layer extending another) are upserted even in
+// truncate mode, so their rows replace base rows with the same primary key.
+// Rows of tables without a primary key are added to the base rows.
+func (s *Seeder) loadSeedFile(ctx context.Context, tx *sql.Tx, file string, opts ApplyOptions, overlay bool) error {
+	f, err := openSeedFile(file)
+	if err != nil {
+		return fmt.Errorf("opening seed file: %w", err)
+	}
+	defer f.Close()
+
+	mode, onConflict := opts.Mode, opts.OnConflict
+	if overlay && mode != ModeMerge {
+		mode, onConflict = ModeMerge, ConflictUpdate
+	}
+
+	primaryKeys := make(map[string][]string)
+	section := &tableSection{}
+	reader := newStatementReader(f)
+	for {
+		stmt, err := reader.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("reading %s (line %d): %w", file, reader.line, err)
+		}
+		if stmt.table != "" && !opts.includesTable(stmt.table) {
+			continue
+		}
+
+		if stmt.table != section.table {
+			section.report(s.stdout, mode)
+			section = &tableSection{table: stmt.table, started: time.Now()}
+		}
+
+		if mode == ModeMerge {
+			err = s.mergeStatement(ctx, tx, stmt, onConflict, primaryKeys, &section.counts)
+		}
+		if mode != ModeMerge || (mode != opts.Mode && errors.Is(err, errNoPrimaryKey)) {
+			_, err = tx.ExecContext(ctx, stmt.sql)
+			if err == nil && insertHeader.MatchString(stmt.sql) {
+				section.counts.inserted += stmt.rows()
+			}
+		}
+		if err != nil {
+			return statementError(file, stmt, err)
+		}
+	}
+	section.report(s.stdout, mode)
+
+	return nil
+}
+
+// tableSection tracks the statements under one "-- Table:" header
+type tableSection struct {
+	table   string
+	started time.Time
+	counts  mergeCounts
+}
+
+// report prints the rows loaded into the section's table
+func (t *tableSection) report(w io.Writer, mode ApplyMode) {
+	if t.table == "" {
+		return
+	}
+	elapsed := time.Since(t.started).Round(time.Millisecond)
+	if mode == ModeMerge {
+		fmt.Fprintf(w, "  %s: %d inserted, %d updated, %d skipped (%s)\n",
+			t.table, t.counts.inserted, t.counts.updated, t.counts.skipped, elapsed)
+		return
+	}
+	fmt.Fprintf(w, "  %s: %d rows (%s)\n", t.table, t.counts.inserted, elapsed)
+}
+
+// statementError locates a failed statement in the seed file: its table,
+// the line it starts on and, when Postgres reports an error position inside
+// a multi-row VALUES list, the offending row.
+func statementError(file string, stmt *statement, err error) error {
+	table := stmt.table
+	if m := insertHeader.FindStringSubmatch(stmt.sql); m != nil {
+		schema, name := splitQualifiedName(m[1])
+		table = schema + "." + name
+	}
+
+	location := fmt.Sprintf("statement at %s:%d", file, stmt.line)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		if pos, convErr := strconv.Atoi(pqErr.Position); convErr == nil && pos > 0 {
+			offset := byteOffset(stmt.sql, pos)
+			if row := stmt.rowAt(offset); row > 0 {
+				location += fmt.Sprintf(", row %d of %d", row, stmt.rows())
+			}
+			location += fmt.Sprintf(", error at line %d", stmt.line+strings.Count(stmt.sql[:offset], "\n"))
+		}
+		if pqErr.Detail != "" {
+			err = fmt.Errorf("%w (%s)", err, pqErr.Detail)
+		}
+	}
+
+	if table == "" {
+		return fmt.Errorf("%s: %w", location, err)
+	}
+	return fmt.Errorf("loading %s (%s): %w", table, location, err)
+}
+
+// byteOffset converts a 1-based character position in s, as reported by
+// Postgres, into a byte offset
+func byteOffset(s string, pos int) int {
+	chars := 0
+	for i := range s {
+		if chars == pos-1 {
+			return i
+		}
+		chars++
+	}
+	return len(s)
+}
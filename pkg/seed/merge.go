@@ -0,0 +1,156 @@
+package seed
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lucasefe/seedup/pkg/pgconn"
+)
+
+// errNoPrimaryKey is returned when merging into a table without a primary key
+var errNoPrimaryKey = errors.New("table has no primary key")
+
+// insertHeader matches the start of an INSERT statement as written by
+// Create: the target table and the column list
+var insertHeader = regexp.MustCompile(`(?is)^INSERT\s+INTO\s+(.+?)\s*\((.*?)\)\s*(?:OVERRIDING\s+\w+\s+VALUE\s+)?VALUES\s`)
+
+// identifierPattern matches a quoted or bare SQL identifier
+var identifierPattern = regexp.MustCompile(`"(?:[^"]|"")*"|[A-Za-z_][A-Za-z0-9_$]*`)
+
+// mergeCounts holds the outcome of merging one table's rows
+type mergeCounts struct {
+	inserted int
+	updated  int
+	skipped  int
+}
+
+// mergeStatement executes stmt, rewriting an INSERT into an upsert on the
+// table's primary key, and adds the outcome to counts. Other statements are
+// executed as they are. primaryKeys caches the keys read so far.
+func (s *Seeder) mergeStatement(ctx context.Context, tx *sql.Tx, stmt *statement, onConflict ConflictAction, primaryKeys map[string][]string, counts *mergeCounts) error {
+	m := insertHeader.FindStringSubmatch(stmt.sql)
+	if m == nil {
+		_, err := tx.ExecContext(ctx, stmt.sql)
+		return err
+	}
+
+	schema, table := splitQualifiedName(m[1])
+	name := schema + "." + table
+	pk, ok := primaryKeys[name]
+	if !ok {
+		var err error
+		if pk, err = pgconn.PrimaryKeyColumns(ctx, tx, schema, table); err != nil {
+			return fmt.Errorf("reading primary key of %s: %w", name, err)
+		}
+		primaryKeys[name] = pk
+	}
+	if len(pk) == 0 {
+		return fmt.Errorf("%s: %w, so its rows can't be merged (use --mode=truncate)", name, errNoPrimaryKey)
+	}
+
+	upsert := upsertStatement(stmt.sql, identifierPattern.FindAllString(m[2], -1), pk, onConflict)
+	rows, err := tx.QueryContext(ctx, upsert)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	returned := 0
+	for rows.Next() {
+		var inserted bool
+		if err := rows.Scan(&inserted); err != nil {
+			return fmt.Errorf("reading merge result: %w", err)
+		}
+		returned++
+		if inserted {
+			counts.inserted++
+		} else {
+			counts.updated++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	counts.skipped += stmt.rows() - returned
+	return nil
+}
+
+// upsertStatement rewrites an INSERT into an INSERT ... ON CONFLICT on the
+// primary key. Rows that conflict are updated with the seed's values, or
+// left alone with ConflictNothing. pk must not be empty. The statement
+// returns whether each written row was inserted (xmax = 0) or updated.
+func upsertStatement(insert string, columns, pk []string, onConflict ConflictAction) string {
+	stmt := strings.TrimSuffix(strings.TrimSpace(insert), ";")
+
+	isKey := make(map[string]bool, len(pk))
+	quotedPK := make([]string, len(pk))
+	for i, col := range pk {
+		isKey[col] = true
+		quotedPK[i] = pgconn.QuoteIdentifier(col)
+	}
+
+	var sets []string
+	for _, col := range columns {
+		if isKey[unquoteIdentifier(col)] {
+			continue
+		}
+		sets = append(sets, fmt.Sprintf("%s = EXCLUDED.%s", col, col))
+	}
+
+	switch {
+	case onConflict == ConflictNothing || len(sets) == 0:
+		stmt += "\nON CONFLICT (" + strings.Join(quotedPK, ", ") + ") DO NOTHING"
+	default:
+		stmt += "\nON CONFLICT (" + strings.Join(quotedPK, ", ") + ") DO UPDATE SET " + strings.Join(sets, ", ")
+	}
+
+	return stmt + "\nRETURNING (xmax = 0)"
+}
+
+// checkMergeable refuses to merge into tables without a primary key: their
+// rows can't be matched with existing ones, so every apply would add them
+// again
+func checkMergeable(ctx context.Context, tx *sql.Tx, tables []string) error {
+	var missing []string
+	for _, table := range tables {
+		schema, name := splitQualifiedName(table)
+		pk, err := pgconn.PrimaryKeyColumns(ctx, tx, schema, name)
+		if err != nil {
+			return fmt.Errorf("reading primary key of %s: %w", table, err)
+		}
+		if len(pk) == 0 {
+			missing = append(missing, table)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("merge needs a primary key, but %s have none: use --mode=truncate, or leave them out with --exclude-tables", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// splitQualifiedName splits a possibly quoted "schema"."table" name,
+// defaulting to the public schema
+func splitQualifiedName(name string) (string, string) {
+	parts := identifierPattern.FindAllString(name, -1)
+	switch len(parts) {
+	case 0:
+		return "public", name
+	case 1:
+		return "public", unquoteIdentifier(parts[0])
+	default:
+		return unquoteIdentifier(parts[0]), unquoteIdentifier(parts[1])
+	}
+}
+
+// unquoteIdentifier removes the quotes of a quoted identifier. Bare
+// identifiers are folded to lower case, as Postgres does.
+func unquoteIdentifier(ident string) string {
+	if len(ident) >= 2 && strings.HasPrefix(ident, `"`) && strings.HasSuffix(ident, `"`) {
+		return strings.ReplaceAll(ident[1:len(ident)-1], `""`, `"`)
+	}
+	return strings.ToLower(ident)
+}
@@ -0,0 +1,44 @@
+package seed
+
+import "testing"
+
+func TestUpsertStatement(t *testing.T) {
+	insert := `INSERT INTO "public"."users" ("id", "email") OVERRIDING SYSTEM VALUE VALUES
+    (1, 'a@example.com');`
+
+	tests := []struct {
+		name       string
+		pk         []string
+		onConflict ConflictAction
+		want       string
+	}{
+		{
+			name:       "update",
+			pk:         []string{"id"},
+			onConflict: ConflictUpdate,
+			want:       "ON CONFLICT (\"id\") DO UPDATE SET \"email\" = EXCLUDED.\"email\"",
+		},
+		{
+			name:       "nothing",
+			pk:         []string{"id"},
+			onConflict: ConflictNothing,
+			want:       "ON CONFLICT (\"id\") DO NOTHING",
+		},
+		{
+			name:       "only key columns",
+			pk:         []string{"id", "email"},
+			onConflict: ConflictUpdate,
+			want:       "ON CONFLICT (\"id\", \"email\") DO NOTHING",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := upsertStatement(insert, []string{`"id"`, `"email"`}, tt.pk, tt.onConflict)
+			want := insert[:len(insert)-1] + "\n" + tt.want + "\nRETURNING (xmax = 0)"
+			if got != want {
+				t.Errorf("upsertStatement() =\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
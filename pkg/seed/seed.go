@@ -1,17 +1,36 @@
 package seed
 
 import (
+	"io"
+	"os"
+
 	"github.com/lucasefe/seedup/pkg/migrate"
 )
 
 // Seeder handles seed data operations
 type Seeder struct {
 	migrator *migrate.Migrator
+	stdout   io.Writer
+}
+
+// Option configures a Seeder
+type Option func(*Seeder)
+
+// WithStdout sets the writer for progress output of Apply
+func WithStdout(w io.Writer) Option {
+	return func(s *Seeder) {
+		s.stdout = w
+	}
 }
 
 // New creates a new Seeder
-func New() *Seeder {
-	return &Seeder{
-		migrator: migrate.New(),
+func New(opts ...Option) *Seeder {
+	s := &Seeder{
+		stdout: os.Stdout,
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	s.migrator = migrate.New(migrate.WithStdout(s.stdout))
+	return s
 }
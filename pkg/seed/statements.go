@@ -0,0 +1,315 @@
+package seed
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+)
+
+// statement is a single SQL statement read from a seed file
+type statement struct {
+	// sql is the statement text, including the terminating semicolon
+	sql string
+	// table is the table named by the closest preceding "-- Table:" header
+	table string
+	// line is the 1-based line of the seed file where the statement starts
+	line int
+	// rowStarts holds the byte offset in sql of each row tuple of an
+	// INSERT ... VALUES list
+	rowStarts []int
+}
+
+// rows returns the number of rows in the statement's VALUES list
+func (s *statement) rows() int {
+	return len(s.rowStarts)
+}
+
+// rowAt returns the 1-based index of the VALUES row containing the byte
+// offset, or 0 if offset is before the first row
+func (s *statement) rowAt(offset int) int {
+	row := 0
+	for i, start := range s.rowStarts {
+		if start > offset {
+			break
+		}
+		row = i + 1
+	}
+	return row
+}
+
+// statementReader splits a seed file into statements. It understands
+// quoted strings (including E'...' escapes), quoted identifiers, dollar quoting
+// and comments, so semicolons inside values don't end a statement.
+type statementReader struct {
+	r     *bufio.Reader
+	line  int
+	table string
+}
+
+func newStatementReader(r io.Reader) *statementReader {
+	return &statementReader{r: bufio.NewReaderSize(r, 64*1024), line: 1}
+}
+
+// next returns the next statement, or io.EOF when there are none left
+func (sr *statementReader) next() (*statement, error) {
+	var buf bytes.Buffer
+	var word bytes.Buffer
+	stmt := &statement{}
+	depth := 0
+	inValues := false
+
+	// endWord checks the identifier that just ended for the VALUES keyword
+	endWord := func() {
+		if depth == 0 && strings.EqualFold(word.String(), "values") {
+			inValues = true
+		}
+		word.Reset()
+	}
+
+	for {
+		c, err := sr.r.ReadByte()
+		if err == io.EOF {
+			if strings.TrimSpace(buf.String()) == "" {
+				return nil, io.EOF
+			}
+			// A final statement without a semicolon
+			stmt.sql = buf.String()
+			return stmt, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if isIdentByte(c) {
+			word.WriteByte(c)
+		} else if word.Len() > 0 {
+			endWord()
+		}
+
+		switch {
+		case c == '\n':
+			sr.line++
+			if buf.Len() > 0 {
+				buf.WriteByte(c)
+			}
+			continue
+
+		case buf.Len() == 0 && (c == ' ' || c == '\t' || c == '\r'):
+			continue
+
+		case c == '-' && sr.peekByte() == '-':
+			comment, err := sr.readLine()
+			if err != nil && err != io.EOF {
+				return nil, err
+			}
+			if buf.Len() == 0 {
+				if name, ok := strings.CutPrefix("-"+comment, "-- Table: "); ok {
+					sr.table = strings.TrimSpace(name)
+				}
+				continue
+			}
+			buf.WriteByte(c)
+			buf.WriteString(comment)
+			continue
+
+		case c == '/' && sr.peekByte() == '*':
+			if buf.Len() == 0 {
+				stmt.line = sr.line
+				stmt.table = sr.table
+			}
+			buf.WriteByte(c)
+			if err := sr.copyBlockComment(&buf); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if buf.Len() == 0 {
+			stmt.line = sr.line
+			stmt.table = sr.table
+		}
+
+		switch c {
+		case '\'':
+			// An E'' string allows backslash escapes
+			escapes := false
+			if b := buf.Bytes(); len(b) > 0 && (b[len(b)-1] == 'E' || b[len(b)-1] == 'e') {
+				escapes = len(b) == 1 || !isIdentByte(b[len(b)-2])
+			}
+			buf.WriteByte(c)
+			if err := sr.copyQuoted(&buf, '\'', escapes); err != nil {
+				return nil, err
+			}
+			word.Reset()
+		case '"':
+			buf.WriteByte(c)
+			if err := sr.copyQuoted(&buf, '"', false); err != nil {
+				return nil, err
+			}
+			word.Reset()
+		case '$':
+			buf.WriteByte(c)
+			if tag, ok := sr.readDollarTag(); ok {
+				buf.WriteString(tag)
+				if err := sr.copyDollarQuoted(&buf, tag); err != nil {
+					return nil, err
+				}
+				word.Reset()
+			}
+		case '(':
+			if depth == 0 && inValues {
+				stmt.rowStarts = append(stmt.rowStarts, buf.Len())
+			}
+			depth++
+			buf.WriteByte(c)
+		case ')':
+			depth--
+			buf.WriteByte(c)
+		case ';':
+			buf.WriteByte(c)
+			if depth <= 0 {
+				stmt.sql = buf.String()
+				return stmt, nil
+			}
+		default:
+			buf.WriteByte(c)
+		}
+	}
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// peekByte returns the next byte without consuming it, or 0 at the end
+func (sr *statementReader) peekByte() byte {
+	b, err := sr.r.Peek(1)
+	if err != nil {
+		return 0
+	}
+	return b[0]
+}
+
+// readLine consumes the rest of the current line, including the newline
+func (sr *statementReader) readLine() (string, error) {
+	line, err := sr.r.ReadString('\n')
+	if strings.HasSuffix(line, "\n") {
+		sr.line++
+	}
+	return line, err
+}
+
+// copyQuoted copies a quoted string or identifier up to and including its
+// closing quote. A doubled quote is an escaped quote.
+func (sr *statementReader) copyQuoted(buf *bytes.Buffer, quote byte, escapes bool) error {
+	for {
+		c, err := sr.r.ReadByte()
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		buf.WriteByte(c)
+		switch {
+		case c == '\n':
+			sr.line++
+		case escapes && c == '\\':
+			next, err := sr.r.ReadByte()
+			if err != nil {
+				return unexpectedEOF(err)
+			}
+			if next == '\n' {
+				sr.line++
+			}
+			buf.WriteByte(next)
+		case c == quote:
+			if sr.peekByte() != quote {
+				return nil
+			}
+			next, _ := sr.r.ReadByte()
+			buf.WriteByte(next)
+		}
+	}
+}
+
+// copyBlockComment copies a /* */ comment, which may be nested, after its
+// opening slash
+func (sr *statementReader) copyBlockComment(buf *bytes.Buffer) error {
+	depth := 0
+	prev := byte('/')
+	for {
+		c, err := sr.r.ReadByte()
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		buf.WriteByte(c)
+		switch {
+		case c == '\n':
+			sr.line++
+		case prev == '/' && c == '*':
+			depth++
+			c = 0
+		case prev == '*' && c == '/':
+			depth--
+			if depth == 0 {
+				return nil
+			}
+			c = 0
+		}
+		prev = c
+	}
+}
+
+// readDollarTag consumes the rest of a dollar-quote opening tag ("$" or
+// "tag$") after the first "$", if there is one
+func (sr *statementReader) readDollarTag() (string, bool) {
+	for n := 1; ; n++ {
+		peek, err := sr.r.Peek(n)
+		if err != nil || len(peek) < n {
+			return "", false
+		}
+		c := peek[n-1]
+		if c == '$' {
+			tag := string(peek)
+			if _, err := sr.r.Discard(n); err != nil {
+				return "", false
+			}
+			return tag, true
+		}
+		// Tags are identifiers that don't start with a digit, which
+		// leaves $1 as a parameter
+		if !isIdentByte(c) || (n == 1 && c >= '0' && c <= '9') {
+			return "", false
+		}
+	}
+}
+
+// copyDollarQuoted copies a dollar-quoted string body up to and including
+// the closing tag. tag is the opening tag after the first "$".
+func (sr *statementReader) copyDollarQuoted(buf *bytes.Buffer, tag string) error {
+	for {
+		c, err := sr.r.ReadByte()
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		buf.WriteByte(c)
+		if c == '\n' {
+			sr.line++
+		}
+		if c != '$' {
+			continue
+		}
+		peek, _ := sr.r.Peek(len(tag))
+		if string(peek) == tag {
+			sr.r.Discard(len(tag))
+			buf.WriteString(tag)
+			return nil
+		}
+	}
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
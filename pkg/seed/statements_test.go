@@ -0,0 +1,214 @@
+package seed
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// readAll reads every statement of input
+func readAll(t *testing.T, input string) []*statement {
+	t.Helper()
+	reader := newStatementReader(strings.NewReader(input))
+	var stmts []*statement
+	for {
+		stmt, err := reader.next()
+		if err == io.EOF {
+			return stmts
+		}
+		if err != nil {
+			t.Fatalf("reading %q: %v", input, err)
+		}
+		stmts = append(stmts, stmt)
+	}
+}
+
+func TestStatementReader(t *testing.T) {
+	type want struct {
+		sql   string
+		table string
+		line  int
+		rows  int
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		want  []want
+	}{
+		{
+			name:  "semicolon in a string",
+			input: "INSERT INTO t (a) VALUES ('a;b');\nSELECT 1;",
+			want: []want{
+				{sql: "INSERT INTO t (a) VALUES ('a;b');", line: 1, rows: 1},
+				{sql: "SELECT 1;", line: 2},
+			},
+		},
+		{
+			name:  "doubled quote",
+			input: "SELECT 'it''s; fine';SELECT 2;",
+			want: []want{
+				{sql: "SELECT 'it''s; fine';", line: 1},
+				{sql: "SELECT 2;", line: 1},
+			},
+		},
+		{
+			name:  "escape string",
+			input: `SELECT E'a\';b', e'\\';SELECT 2;`,
+			want: []want{
+				{sql: `SELECT E'a\';b', e'\\';`, line: 1},
+				{sql: "SELECT 2;", line: 1},
+			},
+		},
+		{
+			name:  "backslash outside an escape string",
+			input: `SELECT 'a\';SELECT 2;`,
+			want: []want{
+				{sql: `SELECT 'a\';`, line: 1},
+				{sql: "SELECT 2;", line: 1},
+			},
+		},
+		{
+			name:  "quoted identifier",
+			input: `SELECT 1 AS "a;""b";SELECT 2;`,
+			want: []want{
+				{sql: `SELECT 1 AS "a;""b";`, line: 1},
+				{sql: "SELECT 2;", line: 1},
+			},
+		},
+		{
+			name:  "dollar quoting",
+			input: "SELECT $$a;b$$, $q$ it's $$; $q$;\nSELECT 2;",
+			want: []want{
+				{sql: "SELECT $$a;b$$, $q$ it's $$; $q$;", line: 1},
+				{sql: "SELECT 2;", line: 2},
+			},
+		},
+		{
+			name:  "parameter is not a dollar tag",
+			input: "SELECT $1;SELECT $2;",
+			want: []want{
+				{sql: "SELECT $1;", line: 1},
+				{sql: "SELECT $2;", line: 1},
+			},
+		},
+		{
+			name:  "nested block comment",
+			input: "/* a /* b; */ c; */ SELECT 1;\nSELECT 2;",
+			want: []want{
+				{sql: "/* a /* b; */ c; */ SELECT 1;", line: 1},
+				{sql: "SELECT 2;", line: 2},
+			},
+		},
+		{
+			name:  "line comments",
+			input: "-- a; b\nSELECT 1 -- c; d\n;\n-- trailing",
+			want: []want{
+				{sql: "SELECT 1 -- c; d\n;", line: 2},
+			},
+		},
+		{
+			name: "table headers",
+			input: "SET x = 1;\n\n-- Table: public.a\nINSERT INTO a (id) VALUES (1);\n\n" +
+				"-- Table: public.b\nINSERT INTO b (id) VALUES (1), (2);\n",
+			want: []want{
+				{sql: "SET x = 1;", line: 1},
+				{sql: "INSERT INTO a (id) VALUES (1);", table: "public.a", line: 4, rows: 1},
+				{sql: "INSERT INTO b (id) VALUES (1), (2);", table: "public.b", line: 7, rows: 2},
+			},
+		},
+		{
+			name:  "line numbers after multi-line values",
+			input: "-- Table: t\nINSERT INTO t (a) VALUES\n    ('x\ny'),\n    ($$\n$$);\nSELECT 1;",
+			want: []want{
+				{sql: "INSERT INTO t (a) VALUES\n    ('x\ny'),\n    ($$\n$$);", table: "t", line: 2, rows: 2},
+				{sql: "SELECT 1;", table: "t", line: 7},
+			},
+		},
+		{
+			name:  "final statement without a semicolon",
+			input: "SELECT 1;\nSELECT 2\n",
+			want: []want{
+				{sql: "SELECT 1;", line: 1},
+				{sql: "SELECT 2\n", line: 2},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmts := readAll(t, tt.input)
+			if len(stmts) != len(tt.want) {
+				t.Fatalf("got %d statements, want %d", len(stmts), len(tt.want))
+			}
+			for i, w := range tt.want {
+				got := want{sql: stmts[i].sql, table: stmts[i].table, line: stmts[i].line, rows: stmts[i].rows()}
+				if got != w {
+					t.Errorf("statement %d = %+v, want %+v", i, got, w)
+				}
+			}
+		})
+	}
+}
+
+func TestStatementReaderUnterminated(t *testing.T) {
+	for _, input := range []string{"SELECT 'a;", `SELECT "a;`, "SELECT $$a;", "/* a; SELECT 1;"} {
+		reader := newStatementReader(strings.NewReader(input))
+		if _, err := reader.next(); !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("reading %q: got %v, want %v", input, err, io.ErrUnexpectedEOF)
+		}
+	}
+}
+
+func TestStatementRows(t *testing.T) {
+	input := "INSERT INTO t (a, b) VALUES (1, 'x'), (2, '(y)'),\n(3, ARRAY(SELECT 1));"
+	stmts := readAll(t, input)
+	if len(stmts) != 1 {
+		t.Fatalf("got %d statements, want 1", len(stmts))
+	}
+	stmt := stmts[0]
+	if stmt.rows() != 3 {
+		t.Errorf("rows() = %d, want 3", stmt.rows())
+	}
+
+	tests := []struct {
+		at   string // text the offset points at
+		want int
+	}{
+		{"INSERT", 0},
+		{"(a, b)", 0},
+		{"(1, 'x')", 1},
+		{"'x'", 1},
+		{"(2,", 2},
+		{"(y)", 2},
+		{"(3,", 3},
+		{"SELECT 1", 3},
+	}
+	for _, tt := range tests {
+		offset := strings.Index(stmt.sql, tt.at)
+		if got := stmt.rowAt(offset); got != tt.want {
+			t.Errorf("rowAt(%d) at %q = %d, want %d", offset, tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestByteOffset(t *testing.T) {
+	tests := []struct {
+		s    string
+		pos  int
+		want int
+	}{
+		{"SELECT x", 1, 0},
+		{"SELECT x", 8, 7},
+		{"'é', x", 6, 6},
+		{"'€€', x", 7, 10},
+		{"'日本語'", 5, 10},
+		{"abc", 10, 3},
+	}
+	for _, tt := range tests {
+		if got := byteOffset(tt.s, tt.pos); got != tt.want {
+			t.Errorf("byteOffset(%q, %d) = %d, want %d", tt.s, tt.pos, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package seed
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/lucasefe/seedup/pkg/db"
+)
+
+// Target is a database to apply a seed to
+type Target struct {
+	// Name identifies the target in progress output and errors
+	Name string
+	URL  string
+}
+
+// TargetResult is the outcome of applying a seed to one target
+type TargetResult struct {
+	Target   Target
+	Duration time.Duration
+	Err      error
+}
+
+// Targets builds the list of databases to seed: dbURL, every URL in extra
+// and, with alsoTest, dbURL's test database (<db>_test, as created by
+// db setup). Duplicate URLs are dropped.
+func Targets(dbURL string, extra []string, alsoTest bool) ([]Target, error) {
+	urls := append([]string{dbURL}, extra...)
+	if alsoTest {
+		testURL, err := testDatabaseURL(dbURL)
+		if err != nil {
+			return nil, fmt.Errorf("deriving test database URL: %w", err)
+		}
+		urls = append(urls, testURL)
+	}
+
+	var targets []Target
+	seen := make(map[string]bool)
+	names := make(map[string]int)
+	for _, u := range urls {
+		if u == "" || seen[u] {
+			continue
+		}
+		seen[u] = true
+
+		name := fmt.Sprintf("target %d", len(targets)+1)
+		if cfg, err := db.ParseDatabaseURL(u); err == nil && cfg.Database != "" {
+			name = cfg.Database
+			if names[name] > 0 {
+				name = fmt.Sprintf("%s@%s:%s", cfg.Database, cfg.Host, cfg.Port)
+			}
+			names[cfg.Database]++
+		}
+		targets = append(targets, Target{Name: name, URL: u})
+	}
+	return targets, nil
+}
+
+// testDatabaseURL returns dbURL pointing at its test database, <db>_test.
+// The credentials, host and query parameters are kept as they are.
+func testDatabaseURL(dbURL string) (string, error) {
+	cfg, err := db.ParseDatabaseURL(dbURL)
+	if err != nil {
+		return "", err
+	}
+	if cfg.Database == "" {
+		return "", fmt.Errorf("database URL has no database name")
+	}
+	u, err := url.Parse(dbURL)
+	if err != nil {
+		return "", err
+	}
+	u.Path = "/" + cfg.Database + "_test"
+	u.RawPath = ""
+	return u.String(), nil
+}
+
+// migrateMu serializes the initial migration, since goose keeps its
+// configuration in package-level state
+var migrateMu sync.Mutex
+
+// ApplyToTargets applies the seed in seedDir to every target, one after
+// another or, with parallel, concurrently. A single target is applied
+// exactly like ApplyWithOptions. Each target loads in its own
+// transaction, and a failure in one doesn't stop the others. Progress lines
+// are prefixed with the target's name, and a summary is printed at the end.
+//
+// The returned error joins the errors of all failed targets, each prefixed
+// with the target's name.
+func (s *Seeder) ApplyToTargets(ctx context.Context, targets []Target, migrationsDir, seedDir string, opts ApplyOptions, parallel bool) ([]TargetResult, error) {
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no target databases")
+	}
+	if _, err := opts.withDefaults(); err != nil {
+		return nil, err
+	}
+	if len(targets) == 1 {
+		started := time.Now()
+		err := s.ApplyWithOptions(ctx, targets[0].URL, migrationsDir, seedDir, opts)
+		return []TargetResult{{Target: targets[0], Duration: time.Since(started), Err: err}}, err
+	}
+
+	out := &lockedWriter{w: s.stdout}
+	results := make([]TargetResult, len(targets))
+	run := func(i int) {
+		target := targets[i]
+		w := &prefixWriter{w: out, prefix: "[" + target.Name + "] "}
+		seeder := New(WithStdout(w))
+
+		started := time.Now()
+		err := seeder.ApplyWithOptions(ctx, target.URL, migrationsDir, seedDir, opts)
+		w.Flush()
+		results[i] = TargetResult{Target: target, Duration: time.Since(started), Err: err}
+	}
+
+	if parallel {
+		var wg sync.WaitGroup
+		for i := range targets {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				run(i)
+			}()
+		}
+		wg.Wait()
+	} else {
+		for i := range targets {
+			run(i)
+		}
+	}
+
+	var errs []error
+	fmt.Fprintln(s.stdout, "\nSummary:")
+	for _, r := range results {
+		status := "ok"
+		if r.Err != nil {
+			status = "FAILED"
+			errs = append(errs, fmt.Errorf("%s: %w", r.Target.Name, r.Err))
+		}
+		fmt.Fprintf(s.stdout, "  %-30s %-6s %s\n", r.Target.Name, status, r.Duration.Round(time.Millisecond))
+	}
+	if len(errs) > 0 {
+		return results, fmt.Errorf("seed apply failed for %d of %d databases: %w", len(errs), len(targets), errors.Join(errs...))
+	}
+	return results, nil
+}
+
+// lockedWriter serializes writes from concurrent targets
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+// prefixWriter prefixes every complete line with prefix, holding back a
+// trailing partial line until Flush
+type prefixWriter struct {
+	w      io.Writer
+	prefix string
+	buf    bytes.Buffer
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.buf.Write(b)
+	for {
+		i := bytes.IndexByte(p.buf.Bytes(), '\n')
+		if i < 0 {
+			break
+		}
+		line := append([]byte(p.prefix), p.buf.Next(i+1)...)
+		if _, err := p.w.Write(line); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush writes a trailing partial line, if any
+func (p *prefixWriter) Flush() {
+	if p.buf.Len() > 0 {
+		p.Write([]byte("\n"))
+	}
+}
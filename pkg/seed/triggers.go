@@ -0,0 +1,185 @@
+package seed
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lucasefe/seedup/pkg/pgconn"
+)
+
+// TriggerStrategy selects how triggers are kept from firing while seed data
+// is loaded
+type TriggerStrategy string
+
+const (
+	// TriggersAuto uses the first strategy the database user is allowed to
+	// use, in the order ReplicationRole, DisableTrigger, DeferConstraints.
+	// DeferConstraints is only used when every foreign key of the seeded
+	// tables is deferrable. This is the default.
+	TriggersAuto TriggerStrategy = "auto"
+	// TriggersReplicationRole sets session_replication_role to replica,
+	// which skips all triggers, including foreign key checks. It needs
+	// superuser or, on PostgreSQL 15+, SET privilege on the parameter.
+	TriggersReplicationRole TriggerStrategy = "replication-role"
+	// TriggersDisableTrigger runs ALTER TABLE ... DISABLE TRIGGER USER on
+	// every seeded table, and enables them again before committing. It needs
+	// table ownership. Foreign keys are still checked as usual: after each
+	// statement, or at commit for those declared INITIALLY DEFERRED.
+	TriggersDisableTrigger TriggerStrategy = "disable-trigger"
+	// TriggersDeferConstraints runs SET CONSTRAINTS ALL DEFERRED, so
+	// deferrable foreign keys are checked at commit. It fails if a seeded
+	// table has a foreign key that isn't DEFERRABLE, since SET CONSTRAINTS
+	// doesn't affect those. User triggers still fire. It needs no
+	// privileges.
+	TriggersDeferConstraints TriggerStrategy = "defer-constraints"
+)
+
+// triggerStrategies lists the strategies TriggersAuto tries, in order
+var triggerStrategies = []TriggerStrategy{
+	TriggersReplicationRole,
+	TriggersDisableTrigger,
+	TriggersDeferConstraints,
+}
+
+// disableTriggers applies strategy inside tx and returns the strategy used.
+// With TriggersAuto, each strategy is tried in a savepoint and the first one
+// that succeeds is kept.
+func (s *Seeder) disableTriggers(ctx context.Context, tx *sql.Tx, strategy TriggerStrategy, tables []string) (TriggerStrategy, error) {
+	if strategy != TriggersAuto {
+		if err := applyTriggerStrategy(ctx, tx, strategy, tables, true); err != nil {
+			return "", fmt.Errorf("disabling triggers with %s: %w (use --triggers to choose another strategy)", strategy, err)
+		}
+		return strategy, nil
+	}
+
+	var lastErr error
+	for _, candidate := range triggerStrategies {
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT seedup_triggers"); err != nil {
+			return "", fmt.Errorf("creating savepoint: %w", err)
+		}
+		if err := applyTriggerStrategy(ctx, tx, candidate, tables, true); err != nil {
+			lastErr = fmt.Errorf("%s: %w", candidate, err)
+			if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT seedup_triggers"); err != nil {
+				return "", fmt.Errorf("rolling back to savepoint: %w", err)
+			}
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT seedup_triggers"); err != nil {
+			return "", fmt.Errorf("releasing savepoint: %w", err)
+		}
+		return candidate, nil
+	}
+
+	return "", fmt.Errorf("disabling triggers: no strategy is permitted (last tried %w)", lastErr)
+}
+
+// enableTriggers undoes disableTriggers before the transaction commits
+func (s *Seeder) enableTriggers(ctx context.Context, tx *sql.Tx, strategy TriggerStrategy, tables []string) error {
+	if err := applyTriggerStrategy(ctx, tx, strategy, tables, false); err != nil {
+		return fmt.Errorf("re-enabling triggers: %w", err)
+	}
+	return nil
+}
+
+// applyTriggerStrategy disables (or re-enables) triggers with strategy
+func applyTriggerStrategy(ctx context.Context, tx *sql.Tx, strategy TriggerStrategy, tables []string, disable bool) error {
+	switch strategy {
+	case TriggersReplicationRole:
+		role := "replica"
+		if !disable {
+			role = "origin"
+		}
+		_, err := tx.ExecContext(ctx, fmt.Sprintf("SET LOCAL session_replication_role = '%s'", role))
+		return err
+
+	case TriggersDisableTrigger:
+		action := "DISABLE"
+		if !disable {
+			action = "ENABLE"
+		}
+		for _, table := range tables {
+			schema, name := splitQualifiedName(table)
+			stmt := fmt.Sprintf("ALTER TABLE %s.%s %s TRIGGER USER",
+				pgconn.QuoteIdentifier(schema), pgconn.QuoteIdentifier(name), action)
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("%s: %w", table, err)
+			}
+		}
+		return nil
+
+	case TriggersDeferConstraints:
+		// Deferred constraints are checked when the transaction commits.
+		// Foreign keys that aren't deferrable would still be checked row by
+		// row, so refuse them rather than fail halfway through the load.
+		if !disable {
+			return nil
+		}
+		fks, err := nonDeferrableForeignKeys(ctx, tx, tables)
+		if err != nil {
+			return err
+		}
+		if len(fks) > 0 {
+			return fmt.Errorf("foreign keys %s are not DEFERRABLE, so they can't be deferred (make them DEFERRABLE, or use a strategy that needs more privileges)", strings.Join(fks, ", "))
+		}
+		_, err = tx.ExecContext(ctx, "SET CONSTRAINTS ALL DEFERRED")
+		return err
+
+	default:
+		return fmt.Errorf("unknown trigger strategy %q", strategy)
+	}
+}
+
+// nonDeferrableForeignKeys returns the foreign keys of the given tables that
+// SET CONSTRAINTS can't defer, as table.constraint
+func nonDeferrableForeignKeys(ctx context.Context, tx *sql.Tx, tables []string) ([]string, error) {
+	query := `
+		SELECT con.conname
+		FROM pg_constraint con
+		JOIN pg_class c ON c.oid = con.conrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE con.contype = 'f'
+		  AND NOT con.condeferrable
+		  AND n.nspname = $1
+		  AND c.relname = $2
+		ORDER BY con.conname
+	`
+
+	var fks []string
+	for _, table := range tables {
+		schema, name := splitQualifiedName(table)
+		rows, err := tx.QueryContext(ctx, query, schema, name)
+		if err != nil {
+			return nil, fmt.Errorf("reading foreign keys of %s: %w", table, err)
+		}
+		for rows.Next() {
+			var conname string
+			if err := rows.Scan(&conname); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("reading foreign keys of %s: %w", table, err)
+			}
+			fks = append(fks, table+"."+conname)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("reading foreign keys of %s: %w", table, err)
+		}
+	}
+	return fks, nil
+}
+
+// describeTriggerStrategy explains what a strategy does, for progress output
+func describeTriggerStrategy(strategy TriggerStrategy) string {
+	switch strategy {
+	case TriggersReplicationRole:
+		return "session_replication_role = replica"
+	case TriggersDisableTrigger:
+		return "ALTER TABLE ... DISABLE TRIGGER USER"
+	case TriggersDeferConstraints:
+		return "deferred constraints (user triggers still fire)"
+	default:
+		return string(strategy)
+	}
+}